@@ -2,6 +2,8 @@ package attestation
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 
 	"github.com/sigstore/cosign/v2/cmd/cosign/cli/generate"
 	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
@@ -62,6 +64,28 @@ func (a *Attestation) Verify(ctx context.Context, blobRef, sigRef string) error
 	return v.Exec(ctx, blobRef)
 }
 
+// VerifyBlob verifies the signature of the blob against the public key, both the blob and
+// the signature being held in memory rather than in files.
+func VerifyBlob(ctx context.Context, publicKey string, blob, signature []byte) error {
+	a, err := NewAttestation(publicKey)
+	if err != nil {
+		return err
+	}
+	dir, err := os.MkdirTemp("", "catalog-cd-verify-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	blobRef, sigRef := filepath.Join(dir, "blob"), filepath.Join(dir, "blob.sig")
+	if err := os.WriteFile(blobRef, blob, 0o600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(sigRef, signature, 0o600); err != nil {
+		return err
+	}
+	return a.Verify(ctx, blobRef, sigRef)
+}
+
 // NewAttestation instantiate the Attestation helper setting the default parameters expected
 // for signing and verifying resources.
 func NewAttestation(key string) (*Attestation, error) {
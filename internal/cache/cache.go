@@ -0,0 +1,82 @@
+// Package cache implements the on-disk cache of the documents fetched from remote
+// repositories: HTTP responses revalidated with their ETag, and immutable blobs addressed by
+// their URL and checksum.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Cache is a cache directory on disk.
+type Cache struct {
+	dir string
+}
+
+// New instantiates a Cache storing its entries in dir, created on first write.
+func New(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+// Dir returns the cache directory.
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Blob opens the blob addressed by the key parts (typically an URL and a checksum), calling
+// open and storing its content on a cache miss. Errors returned by open are returned as-is.
+func (c *Cache) Blob(open func() (io.ReadCloser, error), key ...string) (io.ReadCloser, error) {
+	path := c.path("blobs", key...)
+	if f, err := os.Open(path); err == nil {
+		return f, nil
+	}
+	body, err := open()
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	if err := writeFile(path, body); err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// RemoveBlob removes the blob addressed by the key parts, for instance when its content turns
+// out to be corrupted, so that it is fetched again.
+func (c *Cache) RemoveBlob(key ...string) error {
+	if err := os.Remove(c.path("blobs", key...)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path returns the path of the entry addressed by the key parts in the kind sub-directory.
+func (c *Cache) path(kind string, key ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(key, "\n")))
+	return filepath.Join(c.dir, kind, hex.EncodeToString(sum[:]))
+}
+
+// writeFile atomically writes the content of r at path, so that concurrent readers never see
+// a partial entry.
+func writeFile(path string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		return errors.Join(err, tmp.Close())
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
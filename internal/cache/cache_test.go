@@ -0,0 +1,156 @@
+package cache_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/cache"
+)
+
+func TestTransportRevalidates(t *testing.T) {
+	requests, notModified := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Link", `<https://example.com/next>; rel="next"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte("releases"))
+	}))
+	t.Cleanup(server.Close)
+
+	client := &http.Client{Transport: cache.New(t.TempDir()).Transport(nil)}
+	for i := 0; i < 3; i++ {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK || string(body) != "releases" {
+			t.Fatalf("Should have served the cached response, got %d: %q", resp.StatusCode, body)
+		}
+		if resp.Header.Get("Link") == "" {
+			t.Fatalf("Should have kept the response headers, got %v", resp.Header)
+		}
+	}
+	if requests != 3 || notModified != 2 {
+		t.Fatalf("Should have revalidated the cached response, got %d requests, %d not modified", requests, notModified)
+	}
+}
+
+func TestTransportCredentials(t *testing.T) {
+	notModified := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte("releases"))
+	}))
+	t.Cleanup(server.Close)
+
+	// Each run gets a new token, the cached responses are revalidated all the same.
+	client := &http.Client{Transport: cache.New(t.TempDir()).Transport(nil)}
+	for _, token := range []string{"token1", "token2"} {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "token "+token)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if notModified != 1 {
+		t.Fatalf("Should have revalidated the cached response with another token, got %d not modified", notModified)
+	}
+}
+
+func TestTransportSkipsRedirects(t *testing.T) {
+	dir := t.TempDir()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/asset" {
+			// a new signed URL on every request
+			http.Redirect(w, r, "/signed?signature="+r.Header.Get("X-Request"), http.StatusFound)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte("catalog"))
+	}))
+	t.Cleanup(server.Close)
+
+	client := &http.Client{Transport: cache.New(dir).Transport(nil)}
+	for _, id := range []string{"1", "2"} {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL+"/asset", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("X-Request", id)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil || string(body) != "catalog" {
+			t.Fatalf("Should have followed the redirect, got %q: %v", body, err)
+		}
+	}
+	if entries, err := os.ReadDir(filepath.Join(dir, "http")); err == nil && len(entries) > 0 {
+		t.Fatalf("Should not have cached the redirected responses, got %d entries", len(entries))
+	}
+}
+
+func TestBlob(t *testing.T) {
+	c := cache.New(t.TempDir())
+	opened := 0
+	open := func(content string) func() (io.ReadCloser, error) {
+		return func() (io.ReadCloser, error) {
+			opened++
+			return io.NopCloser(strings.NewReader(content)), nil
+		}
+	}
+	for _, tc := range []struct {
+		checksum string
+		expected string
+		opened   int
+	}{
+		{checksum: "sha1", expected: "v1", opened: 1},
+		{checksum: "sha1", expected: "v1", opened: 1},
+		{checksum: "sha2", expected: "v2", opened: 2},
+	} {
+		body, err := c.Blob(open("v"+tc.checksum[3:]), "https://example.com/resources.tar.gz", tc.checksum)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(body)
+		body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != tc.expected || opened != tc.opened {
+			t.Fatalf("Should have got %q after %d fetches, got %q after %d", tc.expected, tc.opened, content, opened)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+)
+
+// response is the metadata stored along with the body of a cached HTTP response.
+type response struct {
+	URL    string      `json:"url"`
+	ETag   string      `json:"etag"`
+	Header http.Header `json:"header"`
+}
+
+// transport revalidates the cached responses with their ETag before serving them.
+type transport struct {
+	cache *Cache
+	next  http.RoundTripper
+}
+
+// Transport wraps next so that GET responses carrying an ETag are stored in the cache, and
+// sent again with If-None-Match on the following requests. A "304 Not Modified" answer is
+// then served from the cache as a "200 OK". The requests following a redirect are not cached:
+// they typically reach signed URLs, expiring and different on every request.
+func (c *Cache) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{cache: c, next: next}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("If-None-Match") != "" || req.Response != nil {
+		return t.next.RoundTrip(req)
+	}
+	// Responses vary with the requested representation. The credentials are left out, they
+	// may change on every run: the server checks them when revalidating.
+	path := t.cache.path("http", req.URL.String(), req.Header.Get("Accept"))
+	cached := loadResponse(path)
+	if cached != nil {
+		req = req.Clone(req.Context())
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		if body, err := os.Open(path); err == nil {
+			resp.Body.Close()
+			return &http.Response{
+				Status:        "200 OK",
+				StatusCode:    http.StatusOK,
+				Proto:         resp.Proto,
+				ProtoMajor:    resp.ProtoMajor,
+				ProtoMinor:    resp.ProtoMinor,
+				Header:        cached.Header,
+				Body:          body,
+				ContentLength: -1,
+				Request:       req,
+			}, nil
+		}
+	}
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || etag == "" {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	if err := writeFile(path, resp.Body); err != nil {
+		return nil, err
+	}
+	header := resp.Header.Clone()
+	header.Del("Content-Length")
+	metadata, err := json.Marshal(response{URL: req.URL.String(), ETag: etag, Header: header})
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFile(path+".json", bytes.NewReader(metadata)); err != nil {
+		return nil, err
+	}
+	body, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = body
+	resp.ContentLength = -1
+	resp.Header = header
+	return resp, nil
+}
+
+// loadResponse loads the metadata of the cached response stored at path, nil if there is
+// none.
+func loadResponse(path string) *response {
+	data, err := os.ReadFile(path + ".json")
+	if err != nil {
+		return nil
+	}
+	r := &response{}
+	if err := json.Unmarshal(data, r); err != nil || r.ETag == "" {
+		return nil
+	}
+	return r
+}
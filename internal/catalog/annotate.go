@@ -0,0 +1,183 @@
+package catalog
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// GeneratedAnnotation holds the time a resource has been generated at, in RFC 3339.
+const GeneratedAnnotation = "catalog-cd.openshift-pipelines.org/generated"
+
+// generatedAt returns the generation time of the resource with the checksum, previously
+// generated as previous: the previous one when the checksum didn't change, for the resource
+// generated again to stay the same, SOURCE_DATE_EPOCH when set, for reproducible catalogs, or
+// the current time.
+func generatedAt(previous resourceFile, checksum string) string {
+	annotations := previous.Metadata.Annotations
+	if t := annotations[GeneratedAnnotation]; t != "" && annotations[ChecksumAnnotation] == checksum {
+		return t
+	}
+	now := time.Now()
+	if epoch, err := strconv.ParseInt(os.Getenv("SOURCE_DATE_EPOCH"), 10, 64); err == nil {
+		now = time.Unix(epoch, 0)
+	}
+	return now.UTC().Format(time.RFC3339)
+}
+
+// writeResource writes the file of a resource to target. A YAML file is annotated first (see
+// Annotate), along with its generation time (see generatedAt).
+func writeResource(target string, data []byte, annotations map[string]string) error {
+	if filepath.Ext(target) == ".yaml" {
+		previous, _ := readResource(target)
+		annotations = maps.Clone(annotations)
+		annotations[GeneratedAnnotation] = generatedAt(previous, annotations[ChecksumAnnotation])
+		var err error
+		if data, err = Annotate(data, annotations); err != nil {
+			return fmt.Errorf("annotating %s: %w", target, err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(target, data, 0o644) // nolint:gosec
+}
+
+// Annotate sets the annotations in the top-level metadata of the resource, creating its
+// annotations when missing. The annotations already set are updated in place, the others are
+// added first, sorted by key, so that annotating again gives the same bytes. The metadata is
+// edited as a YAML node tree, keeping its comments, styles and indentation, and written back in
+// place: the rest of the resource is left as is, byte for byte.
+func Annotate(data []byte, annotations map[string]string) ([]byte, error) {
+	doc := &yaml.Node{}
+	if err := yaml.NewDecoder(bytes.NewReader(data)).Decode(doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode || doc.Content[0].Style&yaml.FlowStyle != 0 {
+		return nil, fmt.Errorf("resource is not a YAML block mapping")
+	}
+	resource := doc.Content[0]
+	i := mappingIndex(resource, "metadata")
+	if i < 0 {
+		return nil, fmt.Errorf("resource has no metadata")
+	}
+	key, metadata := resource.Content[i], resource.Content[i+1]
+	if metadata.Tag == "!!null" {
+		metadata = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+	if metadata.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("metadata is not a mapping, line %d", metadata.Line)
+	}
+	values, err := mappingValue(metadata, "annotations")
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(annotations))
+	for k := range annotations {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	added := []*yaml.Node{}
+	for _, k := range keys {
+		value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Style: yaml.DoubleQuotedStyle, Value: annotations[k]}
+		if j := mappingIndex(values, k); j >= 0 {
+			value.LineComment = values.Content[j+1].LineComment
+			values.Content[j+1] = value
+			continue
+		}
+		added = append(added, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k}, value)
+	}
+	values.Content = append(added, values.Content...)
+
+	// The comments above and below the metadata are not part of its lines.
+	metadataKey := *key
+	metadataKey.HeadComment, metadataKey.FootComment = "", ""
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(indentation(key, metadata))
+	if err := enc.Encode(&yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{&metadataKey, metadata}}); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	first, last := key.Line-1, blockEnd(lines, resource, i)
+	out := strings.Join(lines[:first], "") + b.String() + strings.Join(lines[last:], "")
+	return []byte(out), nil
+}
+
+// blockEnd returns the index of the line following the block of the top-level key at index i
+// of the resource mapping, as given by the node tree: the line of the next top-level key, or
+// the end of the document. The blank lines and the comments at column 0 right before it are
+// excluded, they belong to what follows.
+func blockEnd(lines []string, resource *yaml.Node, i int) int {
+	first := resource.Content[i].Line - 1
+	last := len(lines)
+	if i+2 < len(resource.Content) {
+		last = resource.Content[i+2].Line - 1
+	} else {
+		for j := first + 1; j < len(lines); j++ {
+			if line := strings.TrimRight(lines[j], "\r\n"); line == "---" || line == "..." {
+				last = j
+				break
+			}
+		}
+	}
+	for last > first+1 {
+		line := strings.TrimRight(lines[last-1], "\r\n")
+		if strings.TrimSpace(line) != "" && !strings.HasPrefix(line, "#") {
+			break
+		}
+		last--
+	}
+	return last
+}
+
+// mappingIndex returns the index of the key in the content of the mapping, -1 when missing.
+func mappingIndex(mapping *yaml.Node, key string) int {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// mappingValue returns the mapping value of the key in the mapping, added, or replacing a
+// null value, when missing.
+func mappingValue(mapping *yaml.Node, key string) (*yaml.Node, error) {
+	value := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	i := mappingIndex(mapping, key)
+	switch {
+	case i < 0:
+		mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+	case mapping.Content[i+1].Kind == yaml.MappingNode:
+		return mapping.Content[i+1], nil
+	case mapping.Content[i+1].Tag == "!!null":
+		mapping.Content[i+1] = value
+	default:
+		return nil, fmt.Errorf("%s is not a mapping, line %d", key, mapping.Content[i+1].Line)
+	}
+	return value, nil
+}
+
+// indentation returns the indentation of the metadata block of the key, 2 spaces when it
+// has none.
+func indentation(key, metadata *yaml.Node) int {
+	if metadata.Style&yaml.FlowStyle == 0 && len(metadata.Content) > 0 && metadata.Line > key.Line {
+		if indent := metadata.Content[0].Column - key.Column; indent > 0 {
+			return indent
+		}
+	}
+	return 2
+}
@@ -0,0 +1,210 @@
+package catalog_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/catalog"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestAnnotate(t *testing.T) {
+	annotations := map[string]string{
+		catalog.SourceAnnotation: "https://github.com/org/repo",
+		catalog.TagAnnotation:    "v0.5.0",
+	}
+	for _, tc := range []struct {
+		name     string
+		resource string
+		expected string
+		err      string
+	}{{
+		name: "annotations",
+		resource: `apiVersion: tekton.dev/v1
+kind: Task
+metadata:
+  name: foo
+  annotations:
+    tekton.dev/tags: go
+spec:
+  steps:
+  - name: build
+    image: golang
+`,
+		expected: `apiVersion: tekton.dev/v1
+kind: Task
+metadata:
+  name: foo
+  annotations:
+    catalog-cd.openshift-pipelines.org/tag: "v0.5.0"
+    tekton.dev/source: "https://github.com/org/repo"
+    tekton.dev/tags: go
+spec:
+  steps:
+  - name: build
+    image: golang
+`,
+	}, {
+		name: "no annotations",
+		resource: `kind: Task
+metadata:
+  name: foo
+  labels:
+    app.kubernetes.io/version: "0.5.0"
+spec: {}
+`,
+		expected: `kind: Task
+metadata:
+  name: foo
+  labels:
+    app.kubernetes.io/version: "0.5.0"
+  annotations:
+    catalog-cd.openshift-pipelines.org/tag: "v0.5.0"
+    tekton.dev/source: "https://github.com/org/repo"
+spec: {}
+`,
+	}, {
+		name: "empty annotations",
+		resource: `kind: Task
+metadata:
+  annotations:
+  name: foo
+`,
+		expected: `kind: Task
+metadata:
+  annotations:
+    catalog-cd.openshift-pipelines.org/tag: "v0.5.0"
+    tekton.dev/source: "https://github.com/org/repo"
+  name: foo
+`,
+	}, {
+		name: "indentation and comments",
+		resource: `# the foo task
+kind: Task
+metadata:
+    name: foo # the name
+    annotations:
+        # set by the release
+        tekton.dev/source: github.com/org/old # overridden
+
+# the spec
+spec:
+    description: >-
+        builds foo
+`,
+		expected: `# the foo task
+kind: Task
+metadata:
+    name: foo # the name
+    annotations:
+        catalog-cd.openshift-pipelines.org/tag: "v0.5.0"
+        # set by the release
+        tekton.dev/source: "https://github.com/org/repo" # overridden
+
+# the spec
+spec:
+    description: >-
+        builds foo
+`,
+	}, {
+		name: "flow style",
+		resource: `kind: Task
+metadata: {name: foo, annotations: {tekton.dev/tags: go}}
+spec: {}
+`,
+		expected: `kind: Task
+metadata: {name: foo, annotations: {catalog-cd.openshift-pipelines.org/tag: "v0.5.0", tekton.dev/source: "https://github.com/org/repo", tekton.dev/tags: go}}
+spec: {}
+`,
+	}, {
+		name: "comment at column 0 in the metadata",
+		resource: `kind: Task
+metadata:
+  name: foo
+# a comment
+  labels:
+    a: b
+spec: {}
+`,
+		expected: `kind: Task
+metadata:
+  name: foo
+  # a comment
+  labels:
+    a: b
+  annotations:
+    catalog-cd.openshift-pipelines.org/tag: "v0.5.0"
+    tekton.dev/source: "https://github.com/org/repo"
+spec: {}
+`,
+	}, {
+		name: "metadata last",
+		resource: `kind: Task
+spec: {}
+metadata:
+  name: foo
+
+# the end
+`,
+		expected: `kind: Task
+spec: {}
+metadata:
+  name: foo
+  annotations:
+    catalog-cd.openshift-pipelines.org/tag: "v0.5.0"
+    tekton.dev/source: "https://github.com/org/repo"
+
+# the end
+`,
+	}, {
+		name:     "no metadata",
+		resource: "kind: Task\nspec: {}\n",
+		err:      "resource has no metadata",
+	}, {
+		name:     "annotations list",
+		resource: "kind: Task\nmetadata:\n  annotations: [foo]\n",
+		err:      "annotations is not a mapping, line 3",
+	}, {
+		name:     "not a mapping",
+		resource: "- kind: Task\n",
+		err:      "resource is not a YAML block mapping",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := catalog.Annotate([]byte(tc.resource), annotations)
+			if tc.err != "" {
+				assert.Error(t, err, tc.err)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, string(data), tc.expected)
+
+			// Annotating again leaves the resource as is.
+			again, err := catalog.Annotate(data, annotations)
+			assert.NilError(t, err)
+			assert.Equal(t, string(again), string(data))
+		})
+	}
+}
+
+func TestGenerateFilesystemStable(t *testing.T) {
+	dir := fs.NewDir(t, "catalog")
+	defer dir.Remove()
+
+	file := dir.Join("tasks", "go-crane-image", "0.5.0", "go-crane-image.yaml")
+	if err := catalog.GenerateFilesystem(context.Background(), dir.Path(), layoutCatalog("0.5.0"), "tasks", catalog.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	generated, err := os.ReadFile(file)
+	assert.NilError(t, err)
+
+	// Generated again later, the resource keeps its generation time.
+	t.Setenv("SOURCE_DATE_EPOCH", "1800000000")
+	if err := catalog.GenerateFilesystem(context.Background(), dir.Path(), layoutCatalog("0.5.0"), "tasks", catalog.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	again, err := os.ReadFile(file)
+	assert.NilError(t, err)
+	assert.Equal(t, string(again), string(generated))
+}
@@ -0,0 +1,73 @@
+package catalog
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// DefaultMaxFileSize caps the size of each file of a resources tarball.
+	DefaultMaxFileSize = 10 << 20
+	// DefaultMaxTotalSize caps the size of all the files of a resources tarball.
+	DefaultMaxTotalSize = 100 << 20
+)
+
+// ErrUnsafeEntry is returned for the entries of a resources tarball that can't be extracted
+// safely: absolute paths, parent directory references, links, special files, and files above
+// the size caps.
+var ErrUnsafeEntry = errors.New("unsafe tarball entry")
+
+// archiveLimits are the size caps of a resources tarball, in bytes.
+type archiveLimits struct {
+	file  int64
+	total int64
+}
+
+// archiveLimits returns the size caps of the resources tarballs, the default ones when unset.
+func (o Options) archiveLimits() archiveLimits {
+	l := archiveLimits{file: o.MaxFileSize, total: o.MaxTotalSize}
+	if l.file <= 0 {
+		l.file = DefaultMaxFileSize
+	}
+	if l.total <= 0 {
+		l.total = DefaultMaxTotalSize
+	}
+	return l
+}
+
+// checkEntry checks that the tarball entry is a directory, a regular file within the caps or
+// a global header, whose path stays in the tarball. total is the size of the entries read so
+// far, this one included.
+func checkEntry(header *tar.Header, total int64, limits archiveLimits) error {
+	unsafe := func(format string, args ...any) error {
+		return fmt.Errorf("%w %q: %s", ErrUnsafeEntry, header.Name, fmt.Sprintf(format, args...))
+	}
+	if strings.HasPrefix(header.Name, "/") {
+		return unsafe("absolute path")
+	}
+	for _, part := range strings.Split(strings.ReplaceAll(header.Name, `\`, "/"), "/") {
+		if part == ".." {
+			return unsafe("parent directory reference")
+		}
+	}
+	switch header.Typeflag {
+	case tar.TypeDir, tar.TypeXGlobalHeader:
+		return nil
+	case tar.TypeReg:
+	case tar.TypeSymlink:
+		return unsafe("symbolic link to %q", header.Linkname)
+	case tar.TypeLink:
+		return unsafe("hard link to %q", header.Linkname)
+	default:
+		return unsafe("unsupported type %q", header.Typeflag)
+	}
+	if header.Size > limits.file {
+		return unsafe("%d bytes, above the %d bytes allowed per file", header.Size, limits.file)
+	}
+	if total > limits.total {
+		return unsafe("above the %d bytes allowed in total", limits.total)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package catalog_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/cache"
+	"github.com/openshift-pipelines/catalog-cd/internal/catalog"
+	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+const craftedTask = "apiVersion: tekton.dev/v1\nkind: Task\nmetadata:\n  name: foo\n"
+
+// entry is an entry of a crafted tarball.
+type entry struct {
+	header  tar.Header
+	content string
+}
+
+// file is the regular file entry of the content.
+func file(name, content string) entry {
+	return entry{header: tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(content))}, content: content}
+}
+
+// craftTarball writes the gzipped tarball of the entries in dir.
+func craftTarball(t *testing.T, dir string, entries []entry) string {
+	t.Helper()
+	path := filepath.Join(dir, "resources.tar.gz")
+	f, err := os.Create(path)
+	assert.NilError(t, err)
+	defer f.Close()
+	gzw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gzw)
+	for _, e := range entries {
+		assert.NilError(t, tw.WriteHeader(&e.header))
+		_, err := tw.Write([]byte(e.content))
+		assert.NilError(t, err)
+	}
+	assert.NilError(t, tw.Close())
+	assert.NilError(t, gzw.Close())
+	return path
+}
+
+// craftedCatalog is the catalog of the foo task of the tarball, released as 0.1.0.
+func craftedCatalog(tarball string) catalog.Catalog {
+	sum := sha256.Sum256([]byte(craftedTask))
+	return catalog.Catalog{Repositories: map[string]catalog.Repository{"crafted": {
+		"0.1.0": {
+			RepositoryURL: "https://fake.host/repo",
+			ResourcesURI:  "https://fake.host/repo/resources.tar.gz",
+			Source:        fakeSource{resources: map[string]string{"v0.1.0": tarball}},
+			Version:       fetcher.Version{TagName: "v0.1.0"},
+			Catalog: contract.Catalog{Resources: &contract.Resources{Tasks: []*contract.TektonResource{{
+				Name:     "foo",
+				Filename: "tasks/foo/foo.yaml",
+				Checksum: hex.EncodeToString(sum[:]),
+			}}}},
+		},
+	}}}
+}
+
+func TestGenerateFilesystemUnsafeTarball(t *testing.T) {
+	task := file("tasks/foo/foo.yaml", craftedTask)
+	for _, tc := range []struct {
+		name    string
+		entries []entry
+		opts    catalog.Options
+		err     string
+	}{{
+		name:    "parent directory",
+		entries: []entry{task, file("tasks/foo/../../../evil.yaml", craftedTask)},
+		err:     `unsafe tarball entry "tasks/foo/../../../evil.yaml": parent directory reference`,
+	}, {
+		name:    "absolute path",
+		entries: []entry{file("/tmp/evil.yaml", craftedTask), task},
+		err:     `unsafe tarball entry "/tmp/evil.yaml": absolute path`,
+	}, {
+		name: "symbolic link",
+		entries: []entry{{header: tar.Header{
+			Name: "tasks/foo/foo.yaml", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd",
+		}}},
+		err: `unsafe tarball entry "tasks/foo/foo.yaml": symbolic link to "/etc/passwd"`,
+	}, {
+		name: "hard link",
+		entries: []entry{task, {header: tar.Header{
+			Name: "tasks/foo/README.md", Typeflag: tar.TypeLink, Linkname: "tasks/foo/foo.yaml",
+		}}},
+		err: `unsafe tarball entry "tasks/foo/README.md": hard link to "tasks/foo/foo.yaml"`,
+	}, {
+		name: "special file",
+		entries: []entry{task, {header: tar.Header{
+			Name: "tasks/foo/fifo", Typeflag: tar.TypeFifo,
+		}}},
+		err: `unsafe tarball entry "tasks/foo/fifo": unsupported type '6'`,
+	}, {
+		name:    "file size",
+		entries: []entry{task},
+		opts:    catalog.Options{MaxFileSize: 16},
+		err:     `unsafe tarball entry "tasks/foo/foo.yaml": 59 bytes, above the 16 bytes allowed per file`,
+	}, {
+		name:    "total size",
+		entries: []entry{task, file("tasks/foo/README.md", strings.Repeat("#", 64))},
+		opts:    catalog.Options{MaxTotalSize: 100},
+		err:     `unsafe tarball entry "tasks/foo/README.md": above the 100 bytes allowed in total`,
+	}, {
+		name:    "total size of the ignored files",
+		entries: []entry{file("docs/big.md", strings.Repeat("#", 128)), task},
+		opts:    catalog.Options{MaxTotalSize: 100},
+		err:     `unsafe tarball entry "docs/big.md": above the 100 bytes allowed in total`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := fs.NewDir(t, "crafted")
+			defer dir.Remove()
+			target := dir.Join("catalog")
+
+			tarball := craftTarball(t, dir.Path(), tc.entries)
+			err := catalog.GenerateFilesystem(context.Background(), target, craftedCatalog(tarball), "tasks", tc.opts)
+			assert.Assert(t, errors.Is(err, catalog.ErrUnsafeEntry), "unexpected error: %v", err)
+			assert.ErrorContains(t, err, tc.err)
+
+			// Nothing is written when the tarball is rejected.
+			_, err = os.Stat(filepath.Join(target, "tasks"))
+			assert.Assert(t, os.IsNotExist(err))
+			_, err = os.Stat(filepath.Join(dir.Path(), "evil.yaml"))
+			assert.Assert(t, os.IsNotExist(err))
+		})
+	}
+}
+
+func TestGenerateFilesystemNormalizesModes(t *testing.T) {
+	dir := fs.NewDir(t, "crafted")
+	defer dir.Remove()
+
+	task := file("tasks/foo/foo.yaml", craftedTask)
+	task.header.Mode = 0o4777
+	tarball := craftTarball(t, dir.Path(), []entry{
+		{header: tar.Header{Name: "tasks/", Typeflag: tar.TypeDir, Mode: 0o777}},
+		task,
+	})
+	target := dir.Join("catalog")
+	if err := catalog.GenerateFilesystem(context.Background(), target, craftedCatalog(tarball), "tasks", catalog.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(target, "tasks", "foo", "0.1.0", "foo.yaml"))
+	assert.NilError(t, err)
+	assert.Equal(t, info.Mode()&^os.FileMode(0o644), os.FileMode(0), "unexpected mode %s", info.Mode())
+}
+
+func TestGenerateFilesystemEvictsCorruptedTarball(t *testing.T) {
+	dir := t.TempDir()
+	opts := catalog.Options{Cache: cache.New(filepath.Join(dir, "cache"))}
+	target := filepath.Join(dir, "catalog")
+
+	// The first download is corrupted, it must not be served from the cache afterwards.
+	tarball := craftTarball(t, dir, []entry{file("tasks/foo/foo.yaml", "kind: Task\n")})
+	err := catalog.GenerateFilesystem(context.Background(), target, craftedCatalog(tarball), "tasks", opts)
+	assert.Assert(t, errors.Is(err, catalog.ErrInvalidChecksum), "unexpected error: %v", err)
+
+	craftTarball(t, dir, []entry{file("tasks/foo/foo.yaml", craftedTask)})
+	assert.NilError(t, catalog.GenerateFilesystem(context.Background(), target, craftedCatalog(tarball), "tasks", opts))
+	_, err = os.Stat(filepath.Join(target, "tasks", "foo", "0.1.0", "foo.yaml"))
+	assert.NilError(t, err)
+}
@@ -0,0 +1,190 @@
+package catalog
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+	"github.com/openshift-pipelines/catalog-cd/internal/render"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// ArtifactHubPackageFile is the Artifact Hub metadata of a resource version, written in
+	// its folder.
+	ArtifactHubPackageFile = "artifacthub-pkg.yml"
+	// ArtifactHubRepositoryFile is the Artifact Hub metadata of the repository, written at the
+	// root of the catalog.
+	ArtifactHubRepositoryFile = "artifacthub-repo.yml"
+)
+
+// artifactHubAnnotations are the resource annotations carried over to the Artifact Hub
+// package metadata.
+var artifactHubAnnotations = []string{
+	"tekton.dev/categories",
+	"tekton.dev/displayName",
+	"tekton.dev/pipelines.minVersion",
+	"tekton.dev/platforms",
+	"tekton.dev/tags",
+}
+
+// ArtifactHub configures the Artifact Hub metadata written along the catalog, for it to be
+// listed as a Tekton repository.
+type ArtifactHub struct {
+	// RepositoryID is the ID of the Artifact Hub repository, to claim its ownership.
+	RepositoryID string
+	// Owners are the emails of the repository owners.
+	Owners []string
+}
+
+// artifactHubRepository is the content of the ArtifactHubRepositoryFile.
+type artifactHubRepository struct {
+	RepositoryID string             `json:"repositoryID,omitempty"`
+	Owners       []artifactHubOwner `json:"owners,omitempty"`
+}
+
+type artifactHubOwner struct {
+	Email string `json:"email"`
+}
+
+// artifactHubPackage is the content of the ArtifactHubPackageFile.
+type artifactHubPackage struct {
+	Version     string            `json:"version"`
+	Name        string            `json:"name"`
+	DisplayName string            `json:"displayName,omitempty"`
+	CreatedAt   string            `json:"createdAt"`
+	Description string            `json:"description"`
+	Digest      string            `json:"digest,omitempty"`
+	Keywords    []string          `json:"keywords,omitempty"`
+	Prerelease  bool              `json:"prerelease,omitempty"`
+	Links       []artifactHubLink `json:"links,omitempty"`
+	Readme      string            `json:"readme,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type artifactHubLink struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// writeArtifactHubPackages writes the Artifact Hub metadata of the release version resources,
+// next to each of them.
+func writeArtifactHubPackages(p placer, version string, release Release, resourceType string) error {
+	filenames := []string{}
+	tektonResources := getResourcesFromType(release, resourceType)
+	for filename := range tektonResources {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+	for _, filename := range filenames {
+		file, err := p.resource(filename, version)
+		if err != nil {
+			return err
+		}
+		readmeFile, err := p.readme(filename, version)
+		if err != nil {
+			return err
+		}
+		if err := writeArtifactHubPackage(file, readmeFile, version, tektonResources[filename], release); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeArtifactHubPackage writes the Artifact Hub metadata of the resource version generated
+// in file, from its contract entry and its annotations, in the same folder. The readme is the
+// README of the resource, in readmeFile, or its rendered documentation when it has none. The
+// creation date of existing metadata is kept, so that a resource version generated again
+// stays the same.
+func writeArtifactHubPackage(file, readmeFile, version string, r contract.TektonResource, release Release) error {
+	resource, err := readResource(file)
+	if err != nil {
+		return err
+	}
+	annotations := resource.Metadata.Annotations
+
+	pkg := artifactHubPackage{
+		Version:     version,
+		Name:        resource.Metadata.Name,
+		DisplayName: annotations["tekton.dev/displayName"],
+		Description: resource.Spec.Description,
+		Digest:      resourceChecksum(r),
+		Keywords:    append(splitAnnotation(annotations["tekton.dev/tags"]), splitAnnotation(annotations["tekton.dev/categories"])...),
+		Prerelease:  release.Version.PreRelease || release.Version.Draft,
+		Annotations: map[string]string{},
+	}
+	if pkg.Description == "" {
+		pkg.Description = pkg.Name
+	}
+	if source := annotations[SourceAnnotation]; source != "" {
+		pkg.Links = []artifactHubLink{{Name: "source", URL: source}}
+	}
+	for _, key := range artifactHubAnnotations {
+		if value, ok := annotations[key]; ok {
+			pkg.Annotations[key] = value
+		}
+	}
+	if pkg.Readme, err = readme(readmeFile, file); err != nil {
+		return err
+	}
+
+	target := filepath.Join(filepath.Dir(file), ArtifactHubPackageFile)
+	pkg.CreatedAt = release.Version.PublishedAt.UTC().Format(time.RFC3339)
+	existing := artifactHubPackage{}
+	if data, err := os.ReadFile(target); err == nil && yaml.Unmarshal(data, &existing) == nil && existing.CreatedAt != "" {
+		pkg.CreatedAt = existing.CreatedAt
+	} else if release.Version.PublishedAt.IsZero() {
+		pkg.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	data, err := yaml.Marshal(pkg)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(target, data, 0o644) // nolint:gosec
+}
+
+// readme returns the README of the resource version, or the documentation rendered from the
+// resource file when there is none, or when the layout leaves it out.
+func readme(readmeFile, file string) (string, error) {
+	if readmeFile != "" {
+		data, err := os.ReadFile(readmeFile)
+		if err == nil {
+			return string(data), nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+	}
+	md, err := render.NewMarkdown(nil, file)
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	if err := md.Execute(&b); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// writeArtifactHubRepository writes the Artifact Hub metadata of the repository at the root of
+// the catalog, when there is any to write.
+func writeArtifactHubRepository(path string, a ArtifactHub) error {
+	if a.RepositoryID == "" && len(a.Owners) == 0 {
+		return nil
+	}
+	repository := artifactHubRepository{RepositoryID: a.RepositoryID}
+	for _, email := range a.Owners {
+		repository.Owners = append(repository.Owners, artifactHubOwner{Email: email})
+	}
+	data, err := yaml.Marshal(repository)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(path, ArtifactHubRepositoryFile), data, 0o644) // nolint:gosec
+}
@@ -0,0 +1,65 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+	"github.com/openshift-pipelines/catalog-cd/internal/oci"
+)
+
+// hasBundles returns true when all the resources carry a bundle reference.
+func hasBundles(tektonResources map[string]contract.TektonResource) bool {
+	if len(tektonResources) == 0 {
+		return false
+	}
+	for _, r := range tektonResources {
+		if r.Bundle == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// extractBundles writes the resources pulled from their Tekton bundle, placed in the catalog
+// like the resources extracted from a tarball. The bundle digest verification replaces
+// the contract checksum verification. The registries are reached through the transport. Like
+// for a tarball, nothing is written until every resource is pulled, within the size caps, and
+// verified by v if any.
+func extractBundles(ctx context.Context, log io.Writer, p placer, version string, tektonResources map[string]contract.TektonResource, annotations map[string]string, transport http.RoundTripper, limits archiveLimits, v *signatureVerifier) error {
+	filenames := make([]string, 0, len(tektonResources))
+	for filename := range tektonResources {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+
+	files := make([]extracted, 0, len(filenames))
+	total := int64(0)
+	for _, filename := range filenames {
+		r := tektonResources[filename]
+		// the kind is given by the resource folder: tasks, pipelines or stepactions
+		kind := strings.TrimSuffix(strings.Split(filename, "/")[0], "s")
+		data, err := oci.FetchBundleResource(ctx, r.Bundle, kind, r.Name, limits.file, oci.Transport(transport)...)
+		if err != nil {
+			return err
+		}
+		if total += int64(len(data)); total > limits.total {
+			return fmt.Errorf("%w: %s (%s) is above the %d bytes allowed in total", oci.ErrBundleTooLarge, r.Filename, r.Bundle, limits.total)
+		}
+
+		target, err := p.resource(filename, version)
+		if err != nil {
+			return err
+		}
+		files = append(files, extracted{target: target, data: data, annotations: resourceAnnotations(annotations, r, version), resource: &r})
+		fmt.Fprintf(log, "✅ %s (%s)\n", r.Filename, r.Bundle)
+	}
+	if err := v.check(ctx, log, files, nil); err != nil {
+		return err
+	}
+	return writeExtracted(files)
+}
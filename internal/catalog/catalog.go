@@ -2,24 +2,46 @@ package catalog
 
 import (
 	"archive/tar"
+	"bytes"
 	"compress/gzip"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
-	"net/http"
+	"maps"
 	"os"
 	"path/filepath"
-	"regexp"
+	"slices"
+	"sort"
 	"strings"
 
-	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/openshift-pipelines/catalog-cd/internal/cache"
 	"github.com/openshift-pipelines/catalog-cd/internal/contract"
 	"github.com/openshift-pipelines/catalog-cd/internal/fetcher"
 	"github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
 )
 
+const (
+	// SourceAnnotation is the annotation holding the repository a resource comes from.
+	SourceAnnotation = "tekton.dev/source"
+	// PreviewAnnotation marks the resources coming from a pre-release or a draft release.
+	PreviewAnnotation = "catalog-cd.openshift-pipelines.org/preview"
+	// TagAnnotation holds the tag of the release a resource comes from.
+	TagAnnotation = "catalog-cd.openshift-pipelines.org/tag"
+	// ChecksumAnnotation holds the contract checksum of a generated resource, or its bundle
+	// reference when it has no checksum. It marks the resources generated by catalog-cd.
+	ChecksumAnnotation = "catalog-cd.openshift-pipelines.org/checksum"
+	// VersionAnnotation holds the version of a generated resource in the catalog, whatever the
+	// layout places it at.
+	VersionAnnotation = "catalog-cd.openshift-pipelines.org/version"
+)
+
+// ErrInvalidChecksum is returned when a resource of a tarball doesn't match its contract
+// checksum.
+var ErrInvalidChecksum = errors.New("invalid checksum")
+
 // Catalog represent the list of repositories from which we fetch informations.
 type Catalog struct {
 	Repositories map[string]Repository
@@ -32,71 +54,342 @@ type Repository map[string]Release
 // It mainly holds the pre-loaded catalog information (containing the list of object published, their hash),
 // as well as the URI to download the tarball containing those resources.
 type Release struct {
-	ResourcesURI string
-	Catalog      contract.Catalog
+	// RepositoryURL is the URL of the repository the release comes from, as configured.
+	RepositoryURL string
+	ResourcesURI  string
+	// ResourcesDigest is the expected SHA256 of the resources tarball, when pinned by a lock.
+	ResourcesDigest string
+	// ContractURI and ContractDigest are the location and SHA256 of the contract.
+	ContractURI    string
+	ContractDigest string
+	Catalog        contract.Catalog
+	// Source is where the release has been fetched from, and its resources are downloaded from.
+	Source  fetcher.Source
+	Version fetcher.Version
+	// Trust is the trust of the repository the resources are verified against, nil not to
+	// verify their signatures.
+	Trust *config.Trust
 }
 
-func FetchFromExternals(e config.External, client *api.RESTClient) (Catalog, error) {
+// FetchFromExternals fetches the releases of the external repositories, each repository going
+// through the source selected by sources. Repositories are fetched in parallel, up to the
+// options concurrency, the errors being reported in the order of the repositories.
+func FetchFromExternals(ctx context.Context, e config.External, sources fetcher.SourceFunc, opts Options) (Catalog, error) {
 	c := Catalog{
 		Repositories: map[string]Repository{},
 	}
-	for _, r := range e.Repositories {
+	repositories := make([]Repository, len(e.Repositories))
+	errs := make([]error, len(e.Repositories))
+	names := make([]string, len(e.Repositories))
+	forEach(len(e.Repositories), opts.Concurrency, func(i int) {
+		r := e.Repositories[i]
 		if r.Name == "" {
 			// Name is empty, take the last part of the URL
 			r.Name = filepath.Base(r.URL)
 		}
-		c.Repositories[r.Name] = Repository{}
+		names[i] = r.Name
+		repositories[i], errs[i] = fetchRepository(ctx, r, sources)
+	})
+	if err := errors.Join(errs...); err != nil {
+		return c, err
+	}
+	for i, name := range names {
+		c.Repositories[name] = repositories[i]
+	}
+	return c, nil
+}
 
-		m, err := fetcher.FetchContractsFromRepository(r, client)
-		if err != nil {
-			return c, err
+// fetchRepository fetches the releases of a single repository.
+func fetchRepository(ctx context.Context, r config.Repository, sources fetcher.SourceFunc) (Repository, error) {
+	repository := Repository{}
+	s, err := sources(r)
+	if err != nil {
+		return nil, err
+	}
+	m, err := fetcher.FetchContractsFromRepository(ctx, r, s)
+	if err != nil {
+		return nil, err
+	}
+	for tag, release := range m {
+		version, _ := r.TagVersion(tag)
+		repository[version] = Release{
+			RepositoryURL:  r.URL,
+			ResourcesURI:   release.ResourcesURL,
+			ContractURI:    release.ContractURL,
+			ContractDigest: release.Contract.Digest(),
+			Catalog:        release.Contract.Catalog,
+			Source:         s,
+			Version:        release.Version,
+			Trust:          r.Trust,
+		}
+	}
+	return repository, nil
+}
+
+// job is a release version to extract in the catalog.
+type job struct {
+	name, version string
+	release       Release
+}
+
+// catalogJobs returns the jobs of the release versions of the catalog, sorted by repository
+// then semantic version.
+func catalogJobs(c Catalog) []job {
+	jobs := []job{}
+	names := make([]string, 0, len(c.Repositories))
+	for name := range c.Repositories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		versions := make([]string, 0, len(c.Repositories[name]))
+		for version := range c.Repositories[name] {
+			versions = append(versions, version)
 		}
-		for _, v := range r.IgnoreVersions {
-			// Remove ignored versions from map
-			delete(m, v)
+		sort.Slice(versions, func(i, j int) bool { return versionLess(versions[i], versions[j]) })
+		for _, version := range versions {
+			jobs = append(jobs, job{name: name, version: version, release: c.Repositories[name][version]})
 		}
+	}
+	return jobs
+}
 
-		for version, contract := range m {
-			resourcesDownloaldURI := fmt.Sprintf("%s/releases/download/%s/%s", r.URL, version, r.ResourcesTarballName)
-			version = strings.TrimPrefix(version, "v")
-			c.Repositories[r.Name][version] = Release{
-				ResourcesURI: resourcesDownloaldURI,
-				Catalog:      contract.Catalog,
+// GenerateFilesystem extracts the resources of every release of the catalog in path. Releases
+// are extracted in parallel, up to the options concurrency, their logs being written in the
+// order of a serial run: repositories then versions, sorted by name. The resources are placed
+// following the options layout, which must not place two of them at the same path. Releases
+// failing to be fetched are skipped, the run going on with the others, and reported as a *GenerateError.
+// A release drifting from its lock stops the run before anything is pruned. The catalog index
+// is written at the root of path, from all the resources of the tree (see WriteIndex), along
+// with the Artifact Hub metadata when enabled by the options. The resources of the releases
+// with a trust are written once their signatures are verified (see Options.Verify), the
+// releases rejected by the trust policy failing with ErrSignature.
+//
+// In incremental mode, the releases already generated from the same checksums are not
+// fetched again, the generated resource versions no longer in the catalog are removed, and
+// the run ends with a summary of the added, updated, unchanged and removed resource versions.
+func GenerateFilesystem(ctx context.Context, path string, c Catalog, resourceType string, opts Options) error {
+	p := newPlacer(path, opts.Layout)
+	jobs := catalogJobs(c)
+	expected, err := placements(p, jobs, resourceType, opts.ArtifactHub != nil)
+	if err != nil {
+		return err
+	}
+
+	// Each release logs in its own buffer, flushed as soon as the previous ones are.
+	logs := make([]bytes.Buffer, len(jobs))
+	done := make([]chan struct{}, len(jobs))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+	flushed := make(chan struct{})
+	go func() {
+		defer close(flushed)
+		for i := range jobs {
+			<-done[i]
+			_, _ = logs[i].WriteTo(os.Stderr)
+		}
+	}()
+
+	failures := make([]*Failure, len(jobs))
+	summaries := make([]summary, len(jobs))
+	forEach(len(jobs), opts.Concurrency, func(i int) {
+		defer close(done[i])
+		j, log := jobs[i], &logs[i]
+		if i == 0 || jobs[i-1].name != j.name {
+			fmt.Fprintf(log, "# Fetching resources from %s\n", j.name)
+		}
+		s := summary{}
+		if opts.Incremental {
+			s = compare(p, j.version, getResourcesFromType(j.release, resourceType))
+		}
+		if opts.Incremental && s.unchanged > 0 && s == (summary{unchanged: s.unchanged}) {
+			fmt.Fprintf(log, "## Version %s is up to date\n", j.version)
+		} else {
+			fmt.Fprintf(log, "## Fetching version %s\n", j.version)
+			if err := fetchAndExtract(ctx, log, p, j.release, j.version, resourceType, opts); err != nil {
+				failures[i] = &Failure{Repository: j.name, Version: j.version, ResourcesURI: j.release.ResourcesURI, Err: err}
+				if !errors.Is(err, ErrLockDrift) {
+					fmt.Fprintf(log, "Failed to fetch resource %s: %v, skipping\n", j.release.ResourcesURI, err)
+				}
+				return
+			}
+		}
+		if opts.ArtifactHub != nil {
+			if err := writeArtifactHubPackages(p, j.version, j.release, resourceType); err != nil {
+				failures[i] = &Failure{Repository: j.name, Version: j.version, ResourcesURI: j.release.ResourcesURI, Err: err}
+				fmt.Fprintf(log, "Failed to write the Artifact Hub metadata: %v\n", err)
+				return
 			}
 		}
+		summaries[i] = s
+	})
+	<-flushed
+	gerr := &GenerateError{}
+	for _, f := range failures {
+		if f != nil {
+			gerr.Failures = append(gerr.Failures, *f)
+		}
 	}
-	return c, nil
+	if errors.Is(gerr, ErrLockDrift) {
+		return gerr
+	}
+	if opts.Incremental {
+		if err := pruneAndSummarize(path, resourceType, expected, summaries); err != nil {
+			return err
+		}
+	}
+	if opts.ArtifactHub != nil {
+		if err := writeArtifactHubRepository(path, *opts.ArtifactHub); err != nil {
+			return err
+		}
+	}
+	if err := WriteIndex(path); err != nil {
+		return err
+	}
+	return gerr.orNil()
 }
 
-func GenerateFilesystem(path string, c Catalog, resourceType string) error {
-	for name, repository := range c.Repositories {
-		fmt.Fprintf(os.Stderr, "# Fetching resources from %s\n", name)
-		for version, release := range repository {
-			fmt.Fprintf(os.Stderr, "## Fetching version %s\n", version)
-			if err := fetchAndExtract(path, release, version, resourceType); err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to fetch resource %s: %v, skipping\n", release.ResourcesURI, err)
+// placements returns the targets of the resources of the jobs, checking that the layout places
+// each of them at its own path: a layout holding a single version per minor version needs a
+// single patch version of each to be selected. The Artifact Hub metadata needs each resource in
+// its own folder too.
+func placements(p placer, jobs []job, resourceType string, artifactHub bool) (map[string]bool, error) {
+	targets := map[string]string{}
+	dirs := map[string]string{}
+	for _, j := range jobs {
+		tektonResources := getResourcesFromType(j.release, resourceType)
+		for _, filename := range slices.Sorted(maps.Keys(tektonResources)) {
+			target, err := p.resource(filename, j.version)
+			if err != nil {
+				return nil, err
+			}
+			from := fmt.Sprintf("%s %s (%s)", filename, j.version, j.name)
+			if other, ok := targets[target]; ok {
+				return nil, fmt.Errorf("layout places %s and %s at %s, select a single version of them (e.g. with latest-patch)", other, from, target)
+			}
+			targets[target] = from
+			if !artifactHub {
 				continue
 			}
+			if other, ok := dirs[filepath.Dir(target)]; ok {
+				return nil, fmt.Errorf("layout places %s and %s in %s, Artifact Hub expects a folder per resource version", other, from, filepath.Dir(target))
+			}
+			dirs[filepath.Dir(target)] = from
 		}
 	}
-	return nil
+	expected := make(map[string]bool, len(targets))
+	for target := range targets {
+		expected[target] = true
+	}
+	return expected, nil
 }
 
-func fetchAndExtract(path string, release Release, version, resourceType string) error {
-	resp, err := http.Get(release.ResourcesURI) // nolint:gosec,noctx
+// pruneAndSummarize removes the generated resources of path not expected, and prints the
+// summary of the run.
+func pruneAndSummarize(path, resourceType string, expected map[string]bool, summaries []summary) error {
+	total := summary{}
+	for _, s := range summaries {
+		total = total.add(s)
+	}
+	removed, err := prune(os.Stderr, path, resourceType, expected)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("status error: %v", resp.StatusCode)
-	}
+	total.removed = removed
+	fmt.Fprintf(os.Stderr, "# Summary: %s\n", total)
+	return nil
+}
+
+func fetchAndExtract(ctx context.Context, log io.Writer, p placer, release Release, version, resourceType string, opts Options) error {
 	// Let's get the file we want to fetch from the release object
 	tektonResources := getResourcesFromType(release, resourceType)
-	return untar(path, version, tektonResources, release.ResourcesURI, resp.Body) // Pass release.ResourcesURI to untar
+	body, err := openResources(ctx, release, opts.Cache)
+	if errors.Is(err, fetcher.ErrResourcesNotFound) && release.ResourcesDigest != "" {
+		return fmt.Errorf("%w: locked resources %s are gone: %w", ErrLockDrift, release.ResourcesURI, err)
+	}
+	if errors.Is(err, fetcher.ErrResourcesNotFound) && hasBundles(tektonResources) {
+		fmt.Fprintf(log, "### No resources tarball, pulling resources from their bundle\n")
+		return extractBundles(ctx, log, p, version, tektonResources, releaseAnnotations(release), opts.Registry, opts.archiveLimits(), opts.signatureVerifier(release))
+	}
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	var r io.Reader = body
+	if release.ResourcesDigest != "" {
+		// The tarball is checked against the lock before extracting anything.
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		if digest := hex.EncodeToString(sum[:]); digest != release.ResourcesDigest {
+			err := fmt.Errorf("%w: resources %s sha256 is %s, locked %s", ErrLockDrift, release.ResourcesURI, digest, release.ResourcesDigest)
+			return errors.Join(err, evictResources(release, opts.Cache))
+		}
+		r = bytes.NewReader(data)
+	}
+	err = untar(ctx, log, p, version, tektonResources, releaseAnnotations(release), r, opts.archiveLimits(), opts.signatureVerifier(release))
+	if errors.Is(err, ErrInvalidChecksum) {
+		// A tarball kept in the cache must not be served again once found corrupted.
+		return errors.Join(err, evictResources(release, opts.Cache))
+	}
+	return err
 }
 
-func untar(dst, version string, tektonResources map[string]contract.TektonResource, resourcesURI string, r io.Reader) error {
+// openResources opens the resources tarball of the release, going through blobs when the
+// contract pins every resource checksum: a tarball already on disk is then served as long as
+// the checksums don't change.
+func openResources(ctx context.Context, release Release, blobs *cache.Cache) (io.ReadCloser, error) {
+	open := func() (io.ReadCloser, error) {
+		return release.Source.OpenResources(ctx, release.Version)
+	}
+	checksum, ok := resourcesChecksum(release.Catalog)
+	if blobs == nil || !ok {
+		return open()
+	}
+	return blobs.Blob(open, release.ResourcesURI, release.Version.TagName, checksum)
+}
+
+// evictResources removes the resources tarball of the release from blobs, if openResources
+// kept it there, so that it is downloaded again.
+func evictResources(release Release, blobs *cache.Cache) error {
+	checksum, ok := resourcesChecksum(release.Catalog)
+	if blobs == nil || !ok {
+		return nil
+	}
+	return blobs.RemoveBlob(release.ResourcesURI, release.Version.TagName, checksum)
+}
+
+// resourcesChecksum sums up the checksums of all the resources of the catalog, it reports
+// false if any of them has no checksum.
+func resourcesChecksum(c contract.Catalog) (string, bool) {
+	if c.Resources == nil {
+		return "", false
+	}
+	lines := []string{}
+	for _, resources := range [][]*contract.TektonResource{c.Resources.Tasks, c.Resources.Pipelines, c.Resources.StepActions} {
+		for _, r := range resources {
+			if r.Checksum == "" {
+				return "", false
+			}
+			lines = append(lines, r.Filename+" "+r.Checksum)
+		}
+	}
+	if len(lines) == 0 {
+		return "", false
+	}
+	sort.Strings(lines)
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:]), true
+}
+
+// untar extracts the resources of the release version from the tarball, along with their
+// README. Nothing is written until the whole tarball is read: it is rejected when any entry is
+// unsafe (see checkEntry), when any resource doesn't match its contract checksum, or when the
+// verifier, if any, rejects its signature. The files are written with normalized modes.
+func untar(ctx context.Context, log io.Writer, p placer, version string, tektonResources map[string]contract.TektonResource, annotations map[string]string, r io.Reader, limits archiveLimits, v *signatureVerifier) error {
 	gzr, err := gzip.NewReader(r)
 	if err != nil {
 		return err
@@ -105,12 +398,21 @@ func untar(dst, version string, tektonResources map[string]contract.TektonResour
 
 	tr := tar.NewReader(gzr)
 
+	// the size of the entries read so far, capped to stop decompression bombs
+	total := int64(0)
+	// the files to write, once the tarball is checked
+	files := []extracted{}
+	// the signature files of the resources, when verified
+	signatures := map[string][]byte{}
 	for {
 		header, err := tr.Next()
 		switch {
-		// if no more files are found return
+		// if no more files are found, verify their signatures and write them
 		case errors.Is(err, io.EOF):
-			return nil
+			if err := v.check(ctx, log, files, signatures); err != nil {
+				return err
+			}
+			return writeExtracted(files)
 		// return any other error
 		case err != nil:
 			return err
@@ -119,125 +421,106 @@ func untar(dst, version string, tektonResources map[string]contract.TektonResour
 			continue
 		}
 
-		// the target location where the dir/file should be created
-		filename := filepath.Base(header.Name)
-		versionnedFolder := filepath.Join(filepath.Dir(header.Name), version)
-		targetFolder := filepath.Join(dst, versionnedFolder)
-		target := filepath.Join(targetFolder, filename)
+		total += header.Size
+		if err := checkEntry(header, total, limits); err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if v != nil && v.wants(header.Name, tektonResources) {
+			if signatures[header.Name], err = io.ReadAll(tr); err != nil {
+				return err
+			}
+			continue
+		}
 
+		// the target location where the file should be created, given by the layout
+		filename := filepath.Base(header.Name)
 		tektonResource, ok := tektonResources[header.Name]
-		if !ok && filename != "README.md" {
-			fmt.Fprintf(os.Stderr, "### Ignoring %s (file not present in the catalog file)\n", header.Name)
+		var target string
+		switch {
+		case ok:
+			target, err = p.resource(header.Name, version)
+		case filename == "README.md":
+			target, err = readmeTarget(p, header.Name, version, tektonResources)
+		default:
+			fmt.Fprintf(log, "### Ignoring %s (file not present in the catalog file)\n", header.Name)
 			continue
 		}
-
-		if err := os.MkdirAll(targetFolder, os.ModePerm); err != nil {
+		if err != nil {
 			return err
 		}
-		// the following switch could also be done using fi.Mode(), not sure if there
-		// a benefit of using one vs. the other.
-		// fi := header.FileInfo()
-
-		// check the file type
-		switch header.Typeflag {
-		// if its a dir and it doesn't exist create it
-		// FIXME: we probably can ignore this.
-		case tar.TypeDir:
-			if _, err := os.Stat(target); err != nil {
-				if err := os.MkdirAll(target, 0o755); err != nil {
-					return err
-				}
-			}
-		// if it's a file create it
-		case tar.TypeReg:
-			f, err := os.OpenFile(target, os.O_CREATE|os.O_RDWR, os.FileMode(header.Mode))
-			if err != nil {
-				return err
-			}
-
-			h := sha256.New()
-			r := io.TeeReader(tr, h)
-			// copy over contents
-			if _, err := io.Copy(f, r); err != nil { // nolint:gosec
-				return err
-			}
-			sum := hex.EncodeToString(h.Sum(nil))
-			// manually close here after each file operation; defering would cause each file close
-			// to wait until all operations have completed.
-			f.Close()
-
-			if filename != "README.md" {
-				if tektonResource.Checksum != sum {
-					fmt.Fprintf(os.Stderr, "%s checksum is different than the specified checksum in the catalog file: %s", sum, tektonResource.Checksum)
-					// FIXME: maybe handle *all* file before erroring out ?
-					return fmt.Errorf("invalid checksum for %s: %s != %s", filename, sum, tektonResource.Checksum)
-				}
-				fmt.Fprintf(os.Stderr, "✅ %s\n", tektonResource.Filename)
-			}
+		if target == "" {
+			// a README without resource, or left out by the layout
+			continue
+		}
 
-			// Add "source" annotation to task YAML file
-			if strings.HasSuffix(target, ".yaml") {
-				if err := addSourceAnnotationToTask(target, resourcesURI); err != nil {
-					return err
-				}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		if filename != "README.md" {
+			sum := sha256.Sum256(data)
+			if digest := hex.EncodeToString(sum[:]); tektonResource.Checksum != digest {
+				fmt.Fprintf(log, "%s checksum is different than the specified checksum in the catalog file: %s", digest, tektonResource.Checksum)
+				return fmt.Errorf("%w for %s: %s != %s", ErrInvalidChecksum, filename, digest, tektonResource.Checksum)
 			}
+			fmt.Fprintf(log, "✅ %s\n", tektonResource.Filename)
+		}
+		f := extracted{target: target, data: data, annotations: resourceAnnotations(annotations, tektonResource, version)}
+		if ok {
+			f.resource = &tektonResource
 		}
+		files = append(files, f)
 	}
 }
 
-func addSourceAnnotationToTask(file, resourcesURI string) error {
-	repoURL := extractRepositoryURL(resourcesURI)
-
-	content, err := os.ReadFile(file)
-	if err != nil {
-		return err
-	}
-
-	lines := strings.Split(string(content), "\n")
-
-	// Match only the top-level metadata annotations block (exactly 2 leading spaces)
-	annotationsPattern := regexp.MustCompile(`^  annotations:\s*$`)
-	sourceAnnotationPattern := regexp.MustCompile(`^    tekton\.dev/source:\s*".*"$`)
+// extracted is a file read from a resources tarball or pulled from a bundle, to be written to
+// target.
+type extracted struct {
+	target      string
+	data        []byte
+	annotations map[string]string
+	// resource is the contract resource of the file, nil for a README
+	resource *contract.TektonResource
+}
 
-	// find the top-level annotations block and check if source already exists
-	annotationsLineIdx := -1
-	sourceAlreadyExists := false
-	for i, line := range lines {
-		if annotationsPattern.MatchString(line) {
-			annotationsLineIdx = i
-			continue
-		}
-		if annotationsLineIdx >= 0 && sourceAnnotationPattern.MatchString(line) {
-			sourceAlreadyExists = true
-			break
-		}
-		// Stop scanning once we leave the annotations block (non-annotation-indented line after annotations:)
-		if annotationsLineIdx >= 0 && !strings.HasPrefix(line, "    ") {
-			break
+// writeExtracted writes the files extracted.
+func writeExtracted(files []extracted) error {
+	for _, f := range files {
+		if err := writeResource(f.target, f.data, f.annotations); err != nil {
+			return err
 		}
 	}
+	return nil
+}
 
-	if sourceAlreadyExists || annotationsLineIdx < 0 {
-		return nil
+// readmeTarget returns the target of the README of the release version, placed along the
+// resource of its folder. It returns an empty target when there is no such resource, or when the
+// layout leaves the README out.
+func readmeTarget(p placer, name, version string, tektonResources map[string]contract.TektonResource) (string, error) {
+	for filename := range tektonResources {
+		if filepath.Dir(filename) == filepath.Dir(name) {
+			return p.readme(filename, version)
+		}
 	}
-
-	// Insert the source annotation right after the annotations: line
-	updatedLines := make([]string, 0, len(lines)+1)
-	updatedLines = append(updatedLines, lines[:annotationsLineIdx+1]...)
-	updatedLines = append(updatedLines, fmt.Sprintf("    tekton.dev/source: \"%s\"", repoURL))
-	updatedLines = append(updatedLines, lines[annotationsLineIdx+1:]...)
-
-	return os.WriteFile(file, []byte(strings.Join(updatedLines, "\n")), 0o644)
+	return "", nil
 }
 
-// Function to extract repository URL from resource tarball URL.
-func extractRepositoryURL(url string) string {
-	// Assuming the resource tarball URL format is consistent: https://github.com/{organization}/{repository}/releases/download/{version}/resources.tar.gz
-	parts := strings.Split(url, "/")
-	if len(parts) < 5 {
-		return ""
-	}
-	return strings.Join(parts[:5], "/")
+// releaseAnnotations returns the annotations added to the resources of the release: their
+// source repository and tag, and whether they come from a preview release.
+func releaseAnnotations(release Release) map[string]string {
+	annotations := map[string]string{
+		SourceAnnotation: release.RepositoryURL,
+	}
+	if release.Version.TagName != "" {
+		annotations[TagAnnotation] = release.Version.TagName
+	}
+	if release.Version.PreRelease || release.Version.Draft {
+		annotations[PreviewAnnotation] = "true"
+	}
+	return annotations
 }
 
 func getResourcesFromType(release Release, resourceType string) map[string]contract.TektonResource {
@@ -1,20 +1,50 @@
 package catalog_test
 
 import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
 	"github.com/openshift-pipelines/catalog-cd/internal/catalog"
 	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher"
 	"github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
+	"github.com/openshift-pipelines/catalog-cd/internal/oci"
 	"gopkg.in/h2non/gock.v1"
 	"gotest.tools/v3/assert"
 	"gotest.tools/v3/fs"
 	"gotest.tools/v3/golden"
+	"sigs.k8s.io/yaml"
 )
 
+func TestMain(m *testing.M) {
+	// The generated resources are compared with golden files: their generation time is fixed.
+	os.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	os.Exit(m.Run())
+}
+
 func TestFetchFromExternal(t *testing.T) {
 	t.Cleanup(gock.Off)
 
@@ -28,8 +58,9 @@ func TestFetchFromExternal(t *testing.T) {
 		Get(fmt.Sprintf("repos/%s/releases", r)).
 		Reply(200).
 		File("testdata/releases.yaml")
-	gock.New("https://github.com").
-		Get(fmt.Sprintf("%s/releases/download/v1.0.0/catalog.yaml", r)).
+	gock.New("https://api.github.com").
+		Get(fmt.Sprintf("repos/%s/releases/assets/1", r)).
+		MatchHeader("Accept", "application/octet-stream").
 		Reply(200).
 		File("testdata/catalog.simple.yaml")
 
@@ -48,23 +79,98 @@ func TestFetchFromExternal(t *testing.T) {
 			ResourcesTarballName: "resources.tar.gz",
 		}},
 	}
-	c, err := catalog.FetchFromExternals(e, client)
+	c, err := catalog.FetchFromExternals(context.Background(), e, fetcher.DefaultSources(fetcher.Clients{
+		GitHub: func(string) (*api.RESTClient, *http.Client, error) { return client, nil, nil },
+	}), catalog.Options{})
 	if err != nil {
 		t.Fatal(err)
 	}
 	if len(c.Repositories) != 1 {
 		t.Fatalf("Should have created a catalog with only 1 repository, got %d: %v", len(c.Repositories), c.Repositories)
 	}
+	expected := "https://github.com/shortbrain/golang-tasks/releases/download/v1.0.0/resources.tar.gz"
+	if uri := c.Repositories["sbr-golang"]["1.0.0"].ResourcesURI; uri != expected {
+		t.Fatalf("Should have resolved the resources from the release assets %s, got %s", expected, uri)
+	}
+	if url := c.Repositories["sbr-golang"]["1.0.0"].RepositoryURL; url != e.Repositories[0].URL {
+		t.Fatalf("Should have kept the configured repository URL %s, got %s", e.Repositories[0].URL, url)
+	}
 }
 
-func TestGenerateFilesystem(t *testing.T) {
-	t.Cleanup(gock.Off)
+// fakeSource is an in-memory fetcher.Source, serving contracts and resources tarballs per tag.
+type fakeSource struct {
+	versions  []fetcher.Version
+	contracts map[string]string // contract file per tag
+	resources map[string]string // resources tarball file per tag
+	assets    map[string]string // asset content per tag and name, as "tag/name"
+}
 
-	gock.New("https://fake.host").
-		Get("resources.tar.gz").
-		Reply(200).
-		File("testdata/resources.tar.gz")
+func (s fakeSource) ListVersions(_ context.Context) ([]fetcher.Version, error) {
+	return s.versions, nil
+}
 
+func (s fakeSource) FetchContract(_ context.Context, v fetcher.Version) (*contract.Contract, error) {
+	file, ok := s.contracts[v.TagName]
+	if !ok {
+		return nil, fetcher.ErrContractNotFound
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return contract.NewContractFromData(data)
+}
+
+func (s fakeSource) OpenResources(_ context.Context, v fetcher.Version) (io.ReadCloser, error) {
+	file, ok := s.resources[v.TagName]
+	if !ok {
+		return nil, fmt.Errorf("%w: no resources for %s", fetcher.ErrResourcesNotFound, v.TagName)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s fakeSource) OpenAsset(_ context.Context, v fetcher.Version, name string) (io.ReadCloser, error) {
+	content, ok := s.assets[v.TagName+"/"+name]
+	if !ok {
+		return nil, fmt.Errorf("%w: no %s asset in %s", fetcher.ErrAssetNotFound, name, v.TagName)
+	}
+	return io.NopCloser(strings.NewReader(content)), nil
+}
+
+// expectedCatalog is the catalog generated from testdata/resources.tar.gz as version 0.5.0.
+func expectedCatalog(t *testing.T) fs.Manifest {
+	t.Helper()
+	return fs.Expected(t, fs.WithDir("stepactions",
+		fs.WithDir("git-clone",
+			fs.WithDir("0.5.0",
+				fs.WithFile("git-clone.yaml", "", fs.WithBytes(golden.Get(t, "stepactions/git-clone/git-clone.yaml"))),
+				fs.WithFile("README.md", "", fs.WithBytes(golden.Get(t, "stepactions/git-clone/README.md"))),
+			),
+		)),
+		fs.WithDir("tasks",
+			fs.WithDir("go-crane-image",
+				fs.WithDir("0.5.0",
+					fs.WithFile("go-crane-image.yaml", "", fs.WithBytes(golden.Get(t, "tasks/go-crane-image/go-crane-image.yaml"))),
+					fs.WithFile("README.md", "", fs.WithBytes(golden.Get(t, "tasks/go-crane-image/README.md"))),
+				),
+			),
+			fs.WithDir("go-ko-image",
+				fs.WithDir("0.5.0",
+					fs.WithFile("go-ko-image.yaml", "", fs.WithBytes(golden.Get(t, "tasks/go-ko-image/go-ko-image.yaml"))),
+					fs.WithFile("README.md", "", fs.WithBytes(golden.Get(t, "tasks/go-ko-image/README.md"))),
+				),
+			),
+		),
+		fs.WithFile(catalog.IndexYAML, "", fs.MatchAnyFileContent),
+		fs.WithFile(catalog.IndexJSON, "", fs.MatchAnyFileContent),
+	)
+}
+
+func TestGenerateFilesystem(t *testing.T) {
 	dir := fs.NewDir(t, "catalog")
 	defer dir.Remove()
 
@@ -72,7 +178,12 @@ func TestGenerateFilesystem(t *testing.T) {
 		Repositories: map[string]catalog.Repository{
 			"sbr-golang": map[string]catalog.Release{
 				"0.5.0": {
-					ResourcesURI: "https://fake.host/repo/resources.tar.gz",
+					RepositoryURL: "https://fake.host/repo",
+					ResourcesURI:  "https://fake.host/repo/resources.tar.gz",
+					Source: fakeSource{
+						resources: map[string]string{"v0.5.0": "testdata/resources.tar.gz"},
+					},
+					Version: fetcher.Version{TagName: "v0.5.0"},
 					Catalog: contract.Catalog{
 						Resources: &contract.Resources{
 							Tasks: []*contract.TektonResource{{
@@ -99,33 +210,543 @@ func TestGenerateFilesystem(t *testing.T) {
 			},
 		},
 	}
-	err := catalog.GenerateFilesystem(dir.Path(), c, "")
+	err := catalog.GenerateFilesystem(context.Background(), dir.Path(), c, "", catalog.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Assert(t, fs.Equal(dir.Path(), expectedCatalog(t)))
+	index, err := os.ReadFile(dir.Join(catalog.IndexYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden.Assert(t, string(index), catalog.IndexYAML)
+}
+
+func TestGenerateFilesystemArtifactHub(t *testing.T) {
+	dir := fs.NewDir(t, "catalog")
+	defer dir.Remove()
+
+	release := catalog.Release{
+		RepositoryURL: "https://fake.host/repo",
+		ResourcesURI:  "https://fake.host/repo/resources.tar.gz",
+		Source: fakeSource{
+			resources: map[string]string{"v0.5.0": "testdata/resources.tar.gz"},
+		},
+		Version: fetcher.Version{TagName: "v0.5.0", PublishedAt: time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)},
+		Catalog: contract.Catalog{
+			Resources: &contract.Resources{
+				Tasks: []*contract.TektonResource{{
+					Name:     "go-crane-image",
+					Version:  "0.5.0",
+					Filename: "tasks/go-crane-image/go-crane-image.yaml",
+					Checksum: "9b1f8e2ecbb5795727de93a6b95bbed2a4f44871f0f0ded6a2d8a04b2283a2b9",
+				}},
+			},
+		},
+	}
+	c := catalog.Catalog{Repositories: map[string]catalog.Repository{"sbr-golang": {"0.5.0": release}}}
+	opts := catalog.Options{ArtifactHub: &catalog.ArtifactHub{RepositoryID: "1234", Owners: []string{"owner@example.com"}}}
+	if err := catalog.GenerateFilesystem(context.Background(), dir.Path(), c, "tasks", opts); err != nil {
+		t.Fatal(err)
+	}
+	pkg, err := os.ReadFile(dir.Join("tasks", "go-crane-image", "0.5.0", catalog.ArtifactHubPackageFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden.Assert(t, string(pkg), catalog.ArtifactHubPackageFile)
+	repo, err := os.ReadFile(dir.Join(catalog.ArtifactHubRepositoryFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(repo), "owners:\n- email: owner@example.com\nrepositoryID: \"1234\"\n")
+
+	// Generated again, the package keeps its creation date.
+	release.Version.PublishedAt = time.Now()
+	c.Repositories["sbr-golang"]["0.5.0"] = release
+	if err := catalog.GenerateFilesystem(context.Background(), dir.Path(), c, "tasks", opts); err != nil {
+		t.Fatal(err)
+	}
+	again, err := os.ReadFile(dir.Join("tasks", "go-crane-image", "0.5.0", catalog.ArtifactHubPackageFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(again), string(pkg))
+}
+
+func TestGenerateFilesystemFromSource(t *testing.T) {
+	dir := fs.NewDir(t, "catalog")
+	defer dir.Remove()
+
+	source := fakeSource{
+		versions: []fetcher.Version{
+			{TagName: "v0.5.0"},
+			{TagName: "v0.6.0-rc.1", PreRelease: true},
+			{TagName: "v0.4.0"},
+		},
+		contracts: map[string]string{
+			"v0.5.0":      "testdata/catalog.simple.yaml",
+			"v0.6.0-rc.1": "testdata/catalog.simple.yaml",
+		},
+		resources: map[string]string{
+			"v0.5.0": "testdata/resources.tar.gz",
+		},
+	}
+	e := config.External{
+		Repositories: []config.Repository{{
+			Name:                 "sbr-golang",
+			URL:                  "https://fake.host/repo",
+			CatalogName:          "catalog.yaml",
+			ResourcesTarballName: "resources.tar.gz",
+		}},
+	}
+	c, err := catalog.FetchFromExternals(context.Background(), e, func(_ config.Repository) (fetcher.Source, error) {
+		return source, nil
+	}, catalog.Options{})
 	if err != nil {
 		t.Fatal(err)
 	}
+	assert.Equal(t, len(c.Repositories["sbr-golang"]), 1)
+
+	if err := catalog.GenerateFilesystem(context.Background(), dir.Path(), c, "", catalog.Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Assert(t, fs.Equal(dir.Path(), expectedCatalog(t)))
+}
+
+func TestGenerateFilesystemPreviewChannel(t *testing.T) {
+	dir := fs.NewDir(t, "catalog")
+	defer dir.Remove()
+
+	source := fakeSource{
+		versions: []fetcher.Version{
+			{TagName: "v0.5.0"},
+			{TagName: "v0.6.0-rc.1", PreRelease: true},
+			{TagName: "v0.7.0", Draft: true},
+		},
+		contracts: map[string]string{
+			"v0.5.0":      "testdata/catalog.simple.yaml",
+			"v0.6.0-rc.1": "testdata/catalog.simple.yaml",
+			"v0.7.0":      "testdata/catalog.simple.yaml",
+		},
+		resources: map[string]string{
+			"v0.5.0":      "testdata/resources.tar.gz",
+			"v0.6.0-rc.1": "testdata/resources.tar.gz",
+			"v0.7.0":      "testdata/resources.tar.gz",
+		},
+	}
+	e := config.External{
+		Channel: config.ChannelPreview,
+		Repositories: []config.Repository{{
+			Name:                 "sbr-golang",
+			URL:                  "https://fake.host/repo",
+			CatalogName:          "catalog.yaml",
+			ResourcesTarballName: "resources.tar.gz",
+			Channel:              config.ChannelPreview,
+		}},
+	}
+	c, err := catalog.FetchFromExternals(context.Background(), e, func(_ config.Repository) (fetcher.Source, error) {
+		return source, nil
+	}, catalog.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(c.Repositories["sbr-golang"]), 2)
+
+	if err := catalog.GenerateFilesystem(context.Background(), dir.Path(), c, "tasks", catalog.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	for version, preview := range map[string]bool{"0.5.0": false, "0.6.0-rc.1": true} {
+		data, err := os.ReadFile(filepath.Join(dir.Path(), "tasks", "go-crane-image", version, "go-crane-image.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, strings.Contains(string(data), catalog.PreviewAnnotation+`: "true"`), preview, version)
+	}
+}
+
+// countingSource counts the resources tarballs opened from its source.
+type countingSource struct {
+	fetcher.Source
+	opened *int
+}
+
+func (s countingSource) OpenResources(ctx context.Context, v fetcher.Version) (io.ReadCloser, error) {
+	*s.opened++
+	return s.Source.OpenResources(ctx, v)
+}
+
+func TestGenerateFilesystemIncremental(t *testing.T) {
+	dir := fs.NewDir(t, "catalog")
+	defer dir.Remove()
+
+	opened := 0
+	source := countingSource{Source: fakeSource{
+		versions:  []fetcher.Version{{TagName: "v0.5.0"}},
+		contracts: map[string]string{"v0.5.0": "testdata/catalog.simple.yaml"},
+		resources: map[string]string{"v0.5.0": "testdata/resources.tar.gz"},
+	}, opened: &opened}
+	e := config.External{
+		Repositories: []config.Repository{{
+			Name:                 "sbr-golang",
+			URL:                  "https://fake.host/repo",
+			CatalogName:          "catalog.yaml",
+			ResourcesTarballName: "resources.tar.gz",
+		}},
+	}
+	c, err := catalog.FetchFromExternals(context.Background(), e, func(_ config.Repository) (fetcher.Source, error) {
+		return source, nil
+	}, catalog.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := catalog.Options{Incremental: true}
+	if err := catalog.GenerateFilesystem(context.Background(), dir.Path(), c, "", opts); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, opened, 1)
+	assert.Assert(t, fs.Equal(dir.Path(), expectedCatalog(t)))
+
+	// A version no longer selected, a resource no longer released, and a resource not
+	// generated by catalog-cd.
+	generated := golden.Get(t, "tasks/go-crane-image/go-crane-image.yaml")
+	for file, data := range map[string][]byte{
+		"tasks/go-crane-image/0.4.0/go-crane-image.yaml": generated,
+		"tasks/go-old-image/0.1.0/go-old-image.yaml":     generated,
+		"tasks/hand-written/0.1.0/hand-written.yaml":     []byte("apiVersion: tekton.dev/v1\nkind: Task\nmetadata:\n  name: hand-written\n"),
+	} {
+		if err := os.MkdirAll(filepath.Dir(dir.Join(file)), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(dir.Join(file), data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := catalog.GenerateFilesystem(context.Background(), dir.Path(), c, "", opts); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, opened, 1, "an up to date release should not be fetched again")
+	_, err = os.Stat(dir.Join("tasks", "go-crane-image", "0.4.0"))
+	assert.Assert(t, os.IsNotExist(err), "the version no longer selected should be removed")
+	_, err = os.Stat(dir.Join("tasks", "go-old-image"))
+	assert.Assert(t, os.IsNotExist(err), "the resource no longer released should be removed")
+	_, err = os.Stat(dir.Join("tasks", "hand-written", "0.1.0", "hand-written.yaml"))
+	assert.NilError(t, err, "the resource not generated by catalog-cd should be kept")
+
+	// The index is rebuilt from the whole catalog, once pruned.
+	index, err := catalog.NewIndex(dir.Path())
+	assert.NilError(t, err)
+	paths := []string{}
+	for _, entry := range index.Resources {
+		paths = append(paths, entry.Path)
+	}
+	assert.DeepEqual(t, paths, []string{
+		"stepactions/git-clone/0.5.0/git-clone.yaml",
+		"tasks/go-crane-image/0.5.0/go-crane-image.yaml",
+		"tasks/go-ko-image/0.5.0/go-ko-image.yaml",
+		"tasks/hand-written/0.1.0/hand-written.yaml",
+	})
+	data, err := os.ReadFile(dir.Join(catalog.IndexJSON))
+	assert.NilError(t, err)
+	written := catalog.Index{}
+	assert.NilError(t, json.Unmarshal(data, &written))
+	assert.DeepEqual(t, written, index)
+}
+
+func TestFetchFromExternalsTagPattern(t *testing.T) {
+	source := fakeSource{
+		versions: []fetcher.Version{
+			{TagName: "go-crane-image/v0.5.0"},
+			{TagName: "git-clone/v1.2.0"},
+			{TagName: "v0.1.0"},
+		},
+		contracts: map[string]string{
+			"go-crane-image/v0.5.0": "testdata/catalog.simple.yaml",
+			"git-clone/v1.2.0":      "testdata/catalog.simple.yaml",
+			"v0.1.0":                "testdata/catalog.simple.yaml",
+		},
+	}
+	e := config.External{
+		Repositories: []config.Repository{{
+			Name:       "go-crane-image",
+			URL:        "https://fake.host/monorepo",
+			TagPattern: "go-crane-image/(?P<version>.*)",
+		}},
+	}
+	c, err := catalog.FetchFromExternals(context.Background(), e, func(_ config.Repository) (fetcher.Source, error) {
+		return source, nil
+	}, catalog.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(c.Repositories["go-crane-image"]), 1)
+	assert.Equal(t, c.Repositories["go-crane-image"]["0.5.0"].Version.TagName, "go-crane-image/v0.5.0")
+}
+
+func TestFetchFromExternalsConcurrently(t *testing.T) {
+	source := fakeSource{
+		versions:  []fetcher.Version{{TagName: "v0.5.0"}},
+		contracts: map[string]string{"v0.5.0": "testdata/catalog.simple.yaml"},
+	}
+	e := config.External{}
+	for i := 0; i < 8; i++ {
+		e.Repositories = append(e.Repositories, config.Repository{URL: fmt.Sprintf("https://fake.host/repo-%d", i)})
+	}
+	sources := func(r config.Repository) (fetcher.Source, error) {
+		if r.Name == "repo-6" || r.Name == "repo-2" {
+			return nil, fmt.Errorf("failed to fetch %s", r.Name)
+		}
+		return source, nil
+	}
+
+	_, err := catalog.FetchFromExternals(context.Background(), e, sources, catalog.Options{Concurrency: 4})
+	assert.Error(t, err, "failed to fetch repo-2\nfailed to fetch repo-6")
+
+	e.Repositories = append(e.Repositories[:6], e.Repositories[7])
+	e.Repositories = append(e.Repositories[:2], e.Repositories[3:]...)
+	c, err := catalog.FetchFromExternals(context.Background(), e, sources, catalog.Options{Concurrency: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(c.Repositories), 6)
+	for _, r := range e.Repositories {
+		assert.Equal(t, len(c.Repositories[filepath.Base(r.URL)]), 1)
+	}
+}
+
+func TestGenerateFilesystemFromLocalDirectory(t *testing.T) {
+	contractFile := fs.WithFile("catalog.yaml", "", fs.WithBytes(golden.Get(t, "catalog.simple.yaml")))
+	resourcesFile := fs.WithFile("resources.tar.gz", "", fs.WithBytes(golden.Get(t, "resources.tar.gz")))
+	mirror := fs.NewDir(t, "mirror", fs.WithDir("golang-tasks",
+		fs.WithDir("v0.4.0", contractFile, resourcesFile),
+		fs.WithDir("v0.5.0", contractFile, resourcesFile),
+		fs.WithDir("v0.6.0", resourcesFile),
+	))
+	defer mirror.Remove()
+	dir := fs.NewDir(t, "catalog")
+	defer dir.Remove()
+
+	e := config.External{
+		Repositories: []config.Repository{{
+			URL:                  "file://" + mirror.Join("golang-tasks"),
+			IgnoreVersions:       []string{"v0.4.0"},
+			CatalogName:          "catalog.yaml",
+			ResourcesTarballName: "resources.tar.gz",
+		}},
+	}
+	c, err := catalog.FetchFromExternals(context.Background(), e, fetcher.DefaultSources(fetcher.Clients{}), catalog.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(c.Repositories["golang-tasks"]), 1)
+
+	if err := catalog.GenerateFilesystem(context.Background(), dir.Path(), c, "", catalog.Options{}); err != nil {
+		t.Fatal(err)
+	}
 
 	expected := fs.Expected(t, fs.WithDir("stepactions",
 		fs.WithDir("git-clone",
 			fs.WithDir("0.5.0",
-				fs.WithFile("git-clone.yaml", "", fs.WithBytes(golden.Get(t, "stepactions/git-clone/git-clone.yaml"))),
+				fs.WithFile("git-clone.yaml", "", fs.MatchAnyFileContent),
 				fs.WithFile("README.md", "", fs.WithBytes(golden.Get(t, "stepactions/git-clone/README.md"))),
 			),
 		)),
 		fs.WithDir("tasks",
 			fs.WithDir("go-crane-image",
 				fs.WithDir("0.5.0",
-					fs.WithFile("go-crane-image.yaml", "", fs.WithBytes(golden.Get(t, "tasks/go-crane-image/go-crane-image.yaml"))),
+					fs.WithFile("go-crane-image.yaml", "", fs.MatchAnyFileContent),
 					fs.WithFile("README.md", "", fs.WithBytes(golden.Get(t, "tasks/go-crane-image/README.md"))),
 				),
 			),
 			fs.WithDir("go-ko-image",
 				fs.WithDir("0.5.0",
-					fs.WithFile("go-ko-image.yaml", "", fs.WithBytes(golden.Get(t, "tasks/go-ko-image/go-ko-image.yaml"))),
+					fs.WithFile("go-ko-image.yaml", "", fs.MatchAnyFileContent),
 					fs.WithFile("README.md", "", fs.WithBytes(golden.Get(t, "tasks/go-ko-image/README.md"))),
 				),
 			),
 		),
+		fs.WithFile(catalog.IndexYAML, "", fs.MatchAnyFileContent),
+		fs.WithFile(catalog.IndexJSON, "", fs.MatchAnyFileContent),
 	)
+	assert.Assert(t, fs.Equal(dir.Path(), expected))
+}
 
+func TestFetchFromExternalsIgnoresVersionsBeforePolicy(t *testing.T) {
+	contractFile := fs.WithFile("catalog.yaml", "", fs.WithBytes(golden.Get(t, "catalog.simple.yaml")))
+	mirror := fs.NewDir(t, "mirror", fs.WithDir("golang-tasks",
+		fs.WithDir("v0.4.0", contractFile),
+		fs.WithDir("v0.5.0", contractFile),
+	))
+	defer mirror.Remove()
+
+	e := config.External{
+		Repositories: []config.Repository{{
+			URL:            "file://" + mirror.Join("golang-tasks"),
+			IgnoreVersions: []string{"v0.5.0"},
+			CatalogName:    "catalog.yaml",
+			// the latest minor is taken among the versions left
+			Versions: config.Versions{LatestMinors: 1},
+		}},
+	}
+	c, err := catalog.FetchFromExternals(context.Background(), e, fetcher.DefaultSources(fetcher.Clients{}), catalog.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.DeepEqual(t, slices.Collect(maps.Keys(c.Repositories["golang-tasks"])), []string{"0.4.0"})
+}
+
+// pushBundle pushes a Tekton bundle holding the task file, returning its reference pinned by
+// digest.
+func pushBundle(t *testing.T, repository, taskName, taskFile string) string {
+	t.Helper()
+	data, err := yaml.YAMLToJSON(golden.Get(t, taskFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	tw := tar.NewWriter(&b)
+	if err := tw.WriteHeader(&tar.Header{Name: taskName, Mode: 0o644, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	img, err := mutate.Append(empty.Image, mutate.Addendum{
+		Layer: static.NewLayer(b.Bytes(), types.OCIUncompressedLayer),
+		Annotations: map[string]string{
+			"dev.tekton.image.apiVersion": "v1",
+			"dev.tekton.image.kind":       "task",
+			"dev.tekton.image.name":       taskName,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag, err := name.NewTag(repository + ":v0.5.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Write(tag, img); err != nil {
+		t.Fatal(err)
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fmt.Sprintf("%s@%s", tag, digest)
+}
+
+func TestGenerateFilesystemFromBundles(t *testing.T) {
+	server := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	dir := fs.NewDir(t, "catalog")
+	defer dir.Remove()
+
+	bundle := pushBundle(t, host+"/go-crane-image", "go-crane-image", "tasks/go-crane-image/go-crane-image.yaml")
+	c := catalog.Catalog{
+		Repositories: map[string]catalog.Repository{
+			"sbr-golang": map[string]catalog.Release{
+				"0.5.0": {
+					RepositoryURL: "https://fake.host/repo",
+					ResourcesURI:  "https://fake.host/repo/resources.tar.gz",
+					Source:        fakeSource{},
+					Version:       fetcher.Version{TagName: "v0.5.0"},
+					Catalog: contract.Catalog{
+						Resources: &contract.Resources{
+							Tasks: []*contract.TektonResource{{
+								Name:     "go-crane-image",
+								Version:  "0.5.0",
+								Filename: "tasks/go-crane-image/go-crane-image.yaml",
+								Bundle:   bundle,
+							}},
+						},
+					},
+				},
+				"0.4.0": {
+					RepositoryURL: "https://fake.host/repo",
+					ResourcesURI:  "https://fake.host/repo/resources.tar.gz",
+					Source:        fakeSource{},
+					Version:       fetcher.Version{TagName: "v0.4.0"},
+					Catalog: contract.Catalog{
+						Resources: &contract.Resources{
+							Tasks: []*contract.TektonResource{{
+								Name:     "go-crane-image",
+								Version:  "0.4.0",
+								Filename: "tasks/go-crane-image/go-crane-image.yaml",
+								// not pinned by digest, can't be verified
+								Bundle: host + "/go-crane-image:v0.5.0",
+							}},
+						},
+					},
+				},
+			},
+		},
+	}
+	// The version whose bundle isn't pinned is skipped, and reported.
+	err := catalog.GenerateFilesystem(context.Background(), dir.Path(), c, "tasks", catalog.Options{})
+	var gerr *catalog.GenerateError
+	assert.Assert(t, errors.As(err, &gerr), err)
+	assert.Equal(t, len(gerr.Failures), 1)
+	assert.Equal(t, gerr.Failures[0].Version, "0.4.0")
+	assert.Assert(t, errors.Is(err, oci.ErrBundleNotPinned))
+
+	expected := fs.Expected(t, fs.WithDir("tasks",
+		fs.WithDir("go-crane-image",
+			fs.WithDir("0.5.0",
+				fs.WithFile("go-crane-image.yaml", "", fs.MatchAnyFileContent),
+			),
+		),
+	), fs.WithFile(catalog.IndexYAML, "", fs.MatchAnyFileContent), fs.WithFile(catalog.IndexJSON, "", fs.MatchAnyFileContent))
 	assert.Assert(t, fs.Equal(dir.Path(), expected))
+
+	data, err := os.ReadFile(dir.Join("tasks", "go-crane-image", "0.5.0", "go-crane-image.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := map[string]any{}
+	if err := yaml.Unmarshal(data, &u); err != nil {
+		t.Fatal(err)
+	}
+	metadata, _ := u["metadata"].(map[string]any)
+	annotations, _ := metadata["annotations"].(map[string]any)
+	assert.Equal(t, metadata["name"], "go-crane-image")
+	assert.Equal(t, annotations["tekton.dev/source"], "https://fake.host/repo")
+}
+
+func TestGenerateFilesystemFromBundlesSizeCaps(t *testing.T) {
+	server := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	bundle := pushBundle(t, host+"/go-crane-image", "go-crane-image", "tasks/go-crane-image/go-crane-image.yaml")
+	c := catalog.Catalog{Repositories: map[string]catalog.Repository{"sbr-golang": {
+		"0.5.0": {
+			RepositoryURL: "https://fake.host/repo",
+			ResourcesURI:  "https://fake.host/repo/resources.tar.gz",
+			Source:        fakeSource{},
+			Version:       fetcher.Version{TagName: "v0.5.0"},
+			Catalog: contract.Catalog{Resources: &contract.Resources{Tasks: []*contract.TektonResource{{
+				Name:     "go-crane-image",
+				Version:  "0.5.0",
+				Filename: "tasks/go-crane-image/go-crane-image.yaml",
+				Bundle:   bundle,
+			}}}},
+		},
+	}}}
+	for _, opts := range []catalog.Options{{MaxFileSize: 64}, {MaxTotalSize: 64}} {
+		dir := fs.NewDir(t, "catalog")
+		defer dir.Remove()
+
+		err := catalog.GenerateFilesystem(context.Background(), dir.Path(), c, "tasks", opts)
+		assert.Assert(t, errors.Is(err, oci.ErrBundleTooLarge), "unexpected error: %v", err)
+		_, err = os.Stat(dir.Join("tasks"))
+		assert.Assert(t, os.IsNotExist(err), "nothing should be written for a bundle too large")
+	}
 }
@@ -0,0 +1,68 @@
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Failure is a release version that failed to be generated.
+type Failure struct {
+	Repository string
+	Version    string
+	// ResourcesURI is where the resources of the version were fetched from.
+	ResourcesURI string
+	Err          error
+}
+
+// Error formats the failure, prefixed with the repository and version.
+func (f Failure) Error() string {
+	return fmt.Sprintf("%s %s: %v", f.Repository, f.Version, f.Err)
+}
+
+// Unwrap returns the cause of the failure.
+func (f Failure) Unwrap() error {
+	return f.Err
+}
+
+// MarshalJSON marshals the failure, its cause being formatted as a message.
+func (f Failure) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Repository string `json:"repository"`
+		Version    string `json:"version"`
+		Resources  string `json:"resources,omitempty"`
+		Error      string `json:"error"`
+	}{f.Repository, f.Version, f.ResourcesURI, f.Err.Error()})
+}
+
+// GenerateError aggregates the release versions that failed to be generated, in the order of
+// the run.
+type GenerateError struct {
+	Failures []Failure
+}
+
+// Error lists the failures, one per line.
+func (e *GenerateError) Error() string {
+	lines := []string{fmt.Sprintf("%d version(s) failed to be generated:", len(e.Failures))}
+	for _, f := range e.Failures {
+		lines = append(lines, "  "+f.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Unwrap returns the failures, so that their causes can be matched with errors.Is.
+func (e *GenerateError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failures))
+	for _, f := range e.Failures {
+		errs = append(errs, f)
+	}
+	return errs
+}
+
+// orNil returns the error when there is any failure, nil otherwise.
+func (e *GenerateError) orNil() error {
+	if len(e.Failures) == 0 {
+		return nil
+	}
+	return e
+}
@@ -0,0 +1,210 @@
+package catalog
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+	"sigs.k8s.io/yaml"
+)
+
+// summary counts the resource versions of a catalog generation, by outcome.
+type summary struct {
+	added     int
+	updated   int
+	unchanged int
+	removed   int
+}
+
+// String formats the summary as a single line.
+func (s summary) String() string {
+	return fmt.Sprintf("%d added, %d updated, %d unchanged, %d removed", s.added, s.updated, s.unchanged, s.removed)
+}
+
+// add sums up the counts of the two summaries.
+func (s summary) add(o summary) summary {
+	return summary{
+		added:     s.added + o.added,
+		updated:   s.updated + o.updated,
+		unchanged: s.unchanged + o.unchanged,
+		removed:   s.removed + o.removed,
+	}
+}
+
+// resourceChecksum returns the value of the checksum annotation of the resource: its contract
+// checksum, or its bundle reference (pinned by digest) when it has no checksum.
+func resourceChecksum(r contract.TektonResource) string {
+	if r.Checksum != "" {
+		return r.Checksum
+	}
+	return r.Bundle
+}
+
+// resourceAnnotations returns the annotations of a generated resource: the release ones, its
+// version and its checksum.
+func resourceAnnotations(annotations map[string]string, r contract.TektonResource, version string) map[string]string {
+	m := maps.Clone(annotations)
+	m[VersionAnnotation] = version
+	m[ChecksumAnnotation] = resourceChecksum(r)
+	return m
+}
+
+// compare reports how the resources of the release version compare with the ones already
+// generated in the catalog.
+func compare(p placer, version string, tektonResources map[string]contract.TektonResource) summary {
+	s := summary{}
+	for filename, r := range tektonResources {
+		switch resourceState(p, version, filename, r) {
+		case ActionAdd:
+			s.added++
+		case ActionUpdate:
+			s.updated++
+		default:
+			s.unchanged++
+		}
+	}
+	return s
+}
+
+// resourceState reports what generating the resource version does to the catalog: the
+// resource is unchanged when its file carries the checksum annotation of the contract, updated
+// when the file exists otherwise, and added when there is none.
+func resourceState(p placer, version, filename string, r contract.TektonResource) Action {
+	file, err := p.resource(filename, version)
+	if err != nil {
+		return ActionUpdate
+	}
+	resource, err := readResource(file)
+	switch {
+	case os.IsNotExist(err):
+		return ActionAdd
+	case err == nil && resourceChecksum(r) != "" && resource.Metadata.Annotations[ChecksumAnnotation] == resourceChecksum(r):
+		return ActionNone
+	default:
+		return ActionUpdate
+	}
+}
+
+// resourceFile holds the fields of a resource file read back from the catalog.
+type resourceFile struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name        string            `json:"name"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+	Spec struct {
+		Description string `json:"description"`
+	} `json:"spec"`
+}
+
+// kindFolder returns the folder of the resource kind in a release: tasks, pipelines or
+// stepactions.
+func (r resourceFile) kindFolder() string {
+	return strings.ToLower(r.Kind) + "s"
+}
+
+// generated reports whether the resource has been generated by catalog-cd, carrying the
+// checksum annotation.
+func (r resourceFile) generated() bool {
+	return r.Metadata.Annotations[ChecksumAnnotation] != ""
+}
+
+// readResource reads the resource file.
+func readResource(file string) (resourceFile, error) {
+	resource := resourceFile{}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return resource, err
+	}
+	err = yaml.Unmarshal(data, &resource)
+	return resource, err
+}
+
+// prune removes from the catalog the generated resource files that are not expected any
+// longer, see stale.
+func prune(log io.Writer, path, resourceType string, expected map[string]bool) (int, error) {
+	files, err := stale(path, resourceType, expected)
+	if err != nil {
+		return 0, err
+	}
+	for i, file := range files {
+		if err := removeResource(path, file); err != nil {
+			return i, err
+		}
+		fmt.Fprintf(log, "🗑 %s\n", strings.TrimPrefix(file, path+string(filepath.Separator)))
+	}
+	return len(files), nil
+}
+
+// removeResource removes the resource file from the catalog in path. The folder of the file
+// goes away with it, README and metadata included, when no other YAML file is left in it, and
+// so do the parent folders left empty.
+func removeResource(path, file string) error {
+	if err := os.Remove(file); err != nil {
+		return err
+	}
+	dir := filepath.Dir(file)
+	if dir == path {
+		return nil
+	}
+	if left, err := filepath.Glob(filepath.Join(dir, "*.yaml")); err != nil || len(left) > 0 {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	for dir = filepath.Dir(dir); dir != path; dir = filepath.Dir(dir) {
+		if entries, err := os.ReadDir(dir); err != nil || len(entries) > 0 {
+			return err
+		}
+		if err := os.Remove(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stale lists the resource files of path, of the resource type, that are not expected any
+// longer. Only the resources generated by catalog-cd are listed: anything else in the catalog
+// is left alone.
+func stale(path, resourceType string, expected map[string]bool) ([]string, error) {
+	files := []string{}
+	err := walkResources(path, func(file string, resource resourceFile) {
+		if resource.generated() && !expected[file] && (resourceType == "" || resource.kindFolder() == resourceType) {
+			files = append(files, file)
+		}
+	})
+	return files, err
+}
+
+// walkResources calls fn with the Tekton resource files of the catalog in path, in lexical
+// order, whatever its layout. The index at its root is skipped, and so are the YAML files that
+// are not Tekton resources.
+func walkResources(path string, fn func(file string, resource resourceFile)) error {
+	err := filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(file) != ".yaml" || file == filepath.Join(path, IndexYAML) {
+			return nil
+		}
+		resource, err := readResource(file)
+		if err != nil || !tektonKinds[resource.Kind] || resource.Metadata.Name == "" {
+			return nil // nolint:nilerr // not a resource, left alone
+		}
+		fn(file, resource)
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// tektonKinds are the kinds of the resources of a catalog.
+var tektonKinds = map[string]bool{"Task": true, "Pipeline": true, "StepAction": true}
@@ -0,0 +1,130 @@
+package catalog
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/blang/semver"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// IndexYAML and IndexJSON are the index files written at the root of the catalog.
+	IndexYAML = "index.yaml"
+	IndexJSON = "index.json"
+)
+
+// Index lists the resource versions of a catalog, so that consumers don't have to walk it.
+type Index struct {
+	Resources []IndexEntry `json:"resources"`
+}
+
+// IndexEntry describes a resource version of the catalog, from the resource file and its
+// annotations.
+type IndexEntry struct {
+	Kind        string   `json:"kind"`
+	Name        string   `json:"name"`
+	Version     string   `json:"version"`
+	Repository  string   `json:"repository,omitempty"`
+	Tag         string   `json:"tag,omitempty"`
+	Checksum    string   `json:"checksum,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Categories  []string `json:"categories,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	// Path is the resource file, relative to the catalog root.
+	Path string `json:"path"`
+}
+
+// NewIndex indexes the Tekton resources of the catalog in path, whatever its layout, sorted by
+// kind, name and version.
+func NewIndex(path string) (Index, error) {
+	index := Index{Resources: []IndexEntry{}}
+	err := walkResources(path, func(file string, resource resourceFile) {
+		index.Resources = append(index.Resources, indexEntry(path, file, resource))
+	})
+	sort.SliceStable(index.Resources, func(i, j int) bool {
+		a, b := index.Resources[i], index.Resources[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return versionLess(a.Version, b.Version)
+	})
+	return index, err
+}
+
+// versionLess reports whether the version a sorts before b, as semantic versions when both
+// are, as strings otherwise.
+func versionLess(a, b string) bool {
+	va, erra := semver.ParseTolerant(a)
+	vb, errb := semver.ParseTolerant(b)
+	if erra != nil || errb != nil {
+		return a < b
+	}
+	return va.LT(vb)
+}
+
+// indexEntry returns the entry of the resource file. The version is given by its annotation,
+// or by its folder for the resources generated before it.
+func indexEntry(path, file string, resource resourceFile) IndexEntry {
+	annotations := resource.Metadata.Annotations
+	version := annotations[VersionAnnotation]
+	if version == "" {
+		version = filepath.Base(filepath.Dir(file))
+	}
+	rel := strings.TrimPrefix(file, path+string(filepath.Separator))
+	return IndexEntry{
+		Kind:        resource.Kind,
+		Name:        resource.Metadata.Name,
+		Version:     version,
+		Repository:  annotations[SourceAnnotation],
+		Tag:         annotations[TagAnnotation],
+		Checksum:    annotations[ChecksumAnnotation],
+		Description: strings.TrimSpace(resource.Spec.Description),
+		Categories:  splitAnnotation(annotations["tekton.dev/categories"]),
+		Tags:        splitAnnotation(annotations["tekton.dev/tags"]),
+		Path:        filepath.ToSlash(rel),
+	}
+}
+
+// splitAnnotation splits the comma-separated values of the annotation.
+func splitAnnotation(value string) []string {
+	values := []string{}
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
+
+// WriteIndex indexes the catalog in path, writing the index as YAML and JSON at its root.
+func WriteIndex(path string) error {
+	if err := os.MkdirAll(path, os.ModePerm); err != nil {
+		return err
+	}
+	index, err := NewIndex(path)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(path, IndexJSON), append(data, '\n'), 0o644); err != nil { // nolint:gosec
+		return err
+	}
+	data, err = yaml.JSONToYAML(data)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(path, IndexYAML), data, 0o644) // nolint:gosec
+}
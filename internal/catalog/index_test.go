@@ -0,0 +1,23 @@
+package catalog_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/catalog"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestNewIndexSortsVersions(t *testing.T) {
+	dir := fs.NewDir(t, "catalog")
+	defer dir.Remove()
+
+	err := catalog.GenerateFilesystem(context.Background(), dir.Path(), layoutCatalog("0.9.0", "0.10.0"), "tasks", catalog.Options{})
+	assert.NilError(t, err)
+	index, err := catalog.NewIndex(dir.Path())
+	assert.NilError(t, err)
+	assert.Equal(t, len(index.Resources), 2)
+	assert.Equal(t, index.Resources[0].Version, "0.9.0")
+	assert.Equal(t, index.Resources[1].Version, "0.10.0")
+}
@@ -0,0 +1,163 @@
+package catalog
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/blang/semver"
+)
+
+// LayoutEntry is a file of a resource version, to be placed in the catalog by a Layout.
+type LayoutEntry struct {
+	// Kind is the folder of the resource in the release: tasks, pipelines or stepactions.
+	Kind string
+	// Name is the name of the resource, given by its folder in the release.
+	Name    string
+	Version string
+	// File is the name of the file in the release: the resource YAML file, or its README.md.
+	File string
+}
+
+// MajorMinor returns the "major.minor" of the entry version, the version as is when it isn't
+// a semantic version.
+func (e LayoutEntry) MajorMinor() string {
+	v, err := semver.ParseTolerant(e.Version)
+	if err != nil {
+		return e.Version
+	}
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+// layoutEntry returns the entry of the file of the release version, given its path in the
+// release (e.g. "tasks/git-clone/git-clone.yaml").
+func layoutEntry(filename, version string) LayoutEntry {
+	return LayoutEntry{
+		Kind:    strings.Split(filepath.ToSlash(filename), "/")[0],
+		Name:    filepath.Base(filepath.Dir(filename)),
+		Version: version,
+		File:    filepath.Base(filename),
+	}
+}
+
+// Layout places the files of the resource versions in the catalog.
+type Layout interface {
+	// Path returns the path of the file in the catalog, relative to its root. An empty path,
+	// or a README placed on its resource file, leaves the file out.
+	Path(e LayoutEntry) (string, error)
+}
+
+// LayoutFunc adapts a function to the Layout interface.
+type LayoutFunc func(e LayoutEntry) (string, error)
+
+// Path calls the function.
+func (f LayoutFunc) Path(e LayoutEntry) (string, error) {
+	return f(e)
+}
+
+var (
+	// DefaultLayout places the files as "<kind>/<name>/<version>/<file>", for instance
+	// "tasks/git-clone/0.5.0/git-clone.yaml".
+	DefaultLayout Layout = LayoutFunc(func(e LayoutEntry) (string, error) {
+		return filepath.Join(e.Kind, e.Name, e.Version, e.File), nil
+	})
+	// TektonCatalogLayout follows the tektoncd/catalog layout "<kind>/<name>/<major.minor>/<file>",
+	// the kind being singular, for instance "task/git-clone/0.5/git-clone.yaml". It holds a
+	// single version per minor version.
+	TektonCatalogLayout Layout = LayoutFunc(func(e LayoutEntry) (string, error) {
+		return filepath.Join(strings.TrimSuffix(e.Kind, "s"), e.Name, e.MajorMinor(), e.File), nil
+	})
+	// FlatLayout places the resource files as "<name>-<version>.yaml" at the root, leaving out
+	// the READMEs.
+	FlatLayout Layout = LayoutFunc(func(e LayoutEntry) (string, error) {
+		if filepath.Ext(e.File) != ".yaml" {
+			return "", nil
+		}
+		return fmt.Sprintf("%s-%s.yaml", e.Name, e.Version), nil
+	})
+)
+
+// NewTemplateLayout returns the layout placing the files at the path given by the Go template
+// pattern, executed with the LayoutEntry, for instance "{{.Kind}}/{{.Name}}-{{.MajorMinor}}/{{.File}}".
+func NewTemplateLayout(pattern string) (Layout, error) {
+	tpl, err := template.New("layout").Option("missingkey=error").Parse(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid layout %q: %w", pattern, err)
+	}
+	return LayoutFunc(func(e LayoutEntry) (string, error) {
+		var b bytes.Buffer
+		if err := tpl.Execute(&b, e); err != nil {
+			return "", err
+		}
+		return filepath.FromSlash(b.String()), nil
+	}), nil
+}
+
+// ParseLayout returns the layout of the given name: "default", "tektoncd" or "flat", or the
+// template layout of the pattern otherwise.
+func ParseLayout(s string) (Layout, error) {
+	switch s {
+	case "", "default":
+		return DefaultLayout, nil
+	case "tektoncd":
+		return TektonCatalogLayout, nil
+	case "flat":
+		return FlatLayout, nil
+	}
+	if !strings.Contains(s, "{{") {
+		return nil, fmt.Errorf("unknown layout %q, default, tektoncd, flat or a Go template expected", s)
+	}
+	return NewTemplateLayout(s)
+}
+
+// placer places the files of the release versions in the catalog root, following the layout.
+type placer struct {
+	root   string
+	layout Layout
+}
+
+// newPlacer returns the placer of the catalog in path, the default layout being used when
+// layout is nil.
+func newPlacer(path string, layout Layout) placer {
+	if layout == nil {
+		layout = DefaultLayout
+	}
+	return placer{root: path, layout: layout}
+}
+
+// resource returns the target of the resource file of the release version.
+func (p placer) resource(filename, version string) (string, error) {
+	target, err := p.place(layoutEntry(filename, version))
+	if err == nil && target == "" {
+		return "", fmt.Errorf("layout leaves out the resource %s %s", filename, version)
+	}
+	return target, err
+}
+
+// readme returns the target of the README of the resource file of the release version, an
+// empty target when the layout leaves it out, or places it on the resource file.
+func (p placer) readme(filename, version string) (string, error) {
+	target, err := p.place(layoutEntry(filepath.Join(filepath.Dir(filename), "README.md"), version))
+	if err != nil || target == "" {
+		return "", err
+	}
+	resource, err := p.resource(filename, version)
+	if err != nil || resource == target {
+		return "", err
+	}
+	return target, nil
+}
+
+// place returns the target of the entry in the catalog root, checking that it stays in it.
+func (p placer) place(e LayoutEntry) (string, error) {
+	rel, err := p.layout.Path(e)
+	if err != nil || rel == "" {
+		return "", err
+	}
+	if !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("layout places %s/%s %s outside of the catalog: %s", e.Kind, e.Name, e.File, rel)
+	}
+	return filepath.Join(p.root, rel), nil
+}
@@ -0,0 +1,146 @@
+package catalog_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/catalog"
+	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/golden"
+)
+
+func TestParseLayout(t *testing.T) {
+	resource := catalog.LayoutEntry{Kind: "tasks", Name: "git-clone", Version: "0.5.1", File: "git-clone.yaml"}
+	readme := catalog.LayoutEntry{Kind: "tasks", Name: "git-clone", Version: "0.5.1", File: "README.md"}
+	for _, tc := range []struct {
+		layout   string
+		resource string
+		readme   string
+		err      string
+	}{{
+		layout:   "default",
+		resource: "tasks/git-clone/0.5.1/git-clone.yaml",
+		readme:   "tasks/git-clone/0.5.1/README.md",
+	}, {
+		layout:   "tektoncd",
+		resource: "task/git-clone/0.5/git-clone.yaml",
+		readme:   "task/git-clone/0.5/README.md",
+	}, {
+		layout:   "flat",
+		resource: "git-clone-0.5.1.yaml",
+	}, {
+		layout:   "{{.Kind}}/{{.Name}}-{{.MajorMinor}}/{{.File}}",
+		resource: "tasks/git-clone-0.5/git-clone.yaml",
+		readme:   "tasks/git-clone-0.5/README.md",
+	}, {
+		layout: "nested",
+		err:    `unknown layout "nested", default, tektoncd, flat or a Go template expected`,
+	}, {
+		layout: "{{.Kind",
+		err:    `invalid layout "{{.Kind": template: layout:1: unclosed action`,
+	}} {
+		t.Run(tc.layout, func(t *testing.T) {
+			layout, err := catalog.ParseLayout(tc.layout)
+			if tc.err != "" {
+				assert.Error(t, err, tc.err)
+				return
+			}
+			assert.NilError(t, err)
+			path, err := layout.Path(resource)
+			assert.NilError(t, err)
+			assert.Equal(t, path, tc.resource)
+			path, err = layout.Path(readme)
+			assert.NilError(t, err)
+			assert.Equal(t, path, tc.readme)
+		})
+	}
+}
+
+// layoutCatalog is a catalog of the go-crane-image task of testdata/resources.tar.gz, released
+// as the given versions.
+func layoutCatalog(versions ...string) catalog.Catalog {
+	repository := catalog.Repository{}
+	for _, version := range versions {
+		repository[version] = catalog.Release{
+			RepositoryURL: "https://fake.host/repo",
+			ResourcesURI:  "https://fake.host/repo/resources.tar.gz",
+			Source: fakeSource{
+				resources: map[string]string{"v" + version: "testdata/resources.tar.gz"},
+			},
+			Version: fetcher.Version{TagName: "v" + version},
+			Catalog: contract.Catalog{
+				Resources: &contract.Resources{
+					Tasks: []*contract.TektonResource{{
+						Name:     "go-crane-image",
+						Filename: "tasks/go-crane-image/go-crane-image.yaml",
+						Checksum: "9b1f8e2ecbb5795727de93a6b95bbed2a4f44871f0f0ded6a2d8a04b2283a2b9",
+					}},
+				},
+			},
+		}
+	}
+	return catalog.Catalog{Repositories: map[string]catalog.Repository{"sbr-golang": repository}}
+}
+
+func TestGenerateFilesystemTektonCatalogLayout(t *testing.T) {
+	dir := fs.NewDir(t, "catalog")
+	defer dir.Remove()
+
+	opts := catalog.Options{Layout: catalog.TektonCatalogLayout, Incremental: true}
+	if err := catalog.GenerateFilesystem(context.Background(), dir.Path(), layoutCatalog("0.5.0"), "tasks", opts); err != nil {
+		t.Fatal(err)
+	}
+	expected := func() fs.Manifest {
+		return fs.Expected(t, fs.WithDir("task",
+			fs.WithDir("go-crane-image",
+				fs.WithDir("0.5",
+					fs.WithFile("go-crane-image.yaml", "", fs.WithBytes(golden.Get(t, "tasks/go-crane-image/go-crane-image.yaml"))),
+					fs.WithFile("README.md", "", fs.WithBytes(golden.Get(t, "tasks/go-crane-image/README.md"))),
+				),
+			),
+		), fs.WithFile(catalog.IndexYAML, "", fs.MatchAnyFileContent), fs.WithFile(catalog.IndexJSON, "", fs.MatchAnyFileContent))
+	}
+	assert.Assert(t, fs.Equal(dir.Path(), expected()))
+
+	index, err := catalog.NewIndex(dir.Path())
+	assert.NilError(t, err)
+	assert.Equal(t, len(index.Resources), 1)
+	assert.Equal(t, index.Resources[0].Version, "0.5.0")
+	assert.Equal(t, index.Resources[0].Path, "task/go-crane-image/0.5/go-crane-image.yaml")
+
+	// Two patch versions of the same minor version can't be placed in the same folder.
+	err = catalog.GenerateFilesystem(context.Background(), dir.Path(), layoutCatalog("0.5.0", "0.5.1"), "tasks", opts)
+	assert.ErrorContains(t, err, "select a single version of them")
+	assert.Assert(t, fs.Equal(dir.Path(), expected()), "nothing should be written on a layout conflict")
+}
+
+func TestGenerateFilesystemFlatLayout(t *testing.T) {
+	dir := fs.NewDir(t, "catalog")
+	defer dir.Remove()
+
+	layout, err := catalog.ParseLayout("flat")
+	assert.NilError(t, err)
+	opts := catalog.Options{Layout: layout, Incremental: true}
+	if err := catalog.GenerateFilesystem(context.Background(), dir.Path(), layoutCatalog("0.4.0", "0.5.0"), "tasks", opts); err != nil {
+		t.Fatal(err)
+	}
+	assert.Assert(t, fs.Equal(dir.Path(), fs.Expected(t,
+		fs.WithFile("go-crane-image-0.4.0.yaml", "", fs.MatchAnyFileContent),
+		fs.WithFile("go-crane-image-0.5.0.yaml", "", fs.MatchAnyFileContent),
+		fs.WithFile(catalog.IndexYAML, "", fs.MatchAnyFileContent),
+		fs.WithFile(catalog.IndexJSON, "", fs.MatchAnyFileContent),
+	)))
+
+	// The version no longer in the catalog is pruned, wherever the layout placed it.
+	if err := catalog.GenerateFilesystem(context.Background(), dir.Path(), layoutCatalog("0.5.0"), "tasks", opts); err != nil {
+		t.Fatal(err)
+	}
+	_, err = os.Stat(dir.Join("go-crane-image-0.4.0.yaml"))
+	assert.Assert(t, os.IsNotExist(err))
+	_, err = os.Stat(dir.Join("go-crane-image-0.5.0.yaml"))
+	assert.NilError(t, err)
+}
@@ -0,0 +1,234 @@
+package catalog
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
+	"sigs.k8s.io/yaml"
+)
+
+// ErrLockDrift marks a release that doesn't match what its lock recorded.
+var ErrLockDrift = errors.New("drift from the lock")
+
+// Lock pins the releases of the external repositories to exact tags and content, so that a
+// catalog can be generated again identically.
+type Lock struct {
+	Repositories []LockedRepository `json:"repositories"`
+}
+
+// LockedRepository holds the releases locked for a repository.
+type LockedRepository struct {
+	Name     string          `json:"name"`
+	URL      string          `json:"url"`
+	Versions []LockedRelease `json:"versions"`
+}
+
+// LockedRelease is a release resolved to its tag, with the location and SHA256 of its contract
+// and resources tarball. The resources are left empty when pulled from their bundles.
+type LockedRelease struct {
+	Version         string `json:"version"`
+	Tag             string `json:"tag"`
+	ContractURL     string `json:"contract-url"`
+	ContractSHA256  string `json:"contract-sha256"`
+	ResourcesURL    string `json:"resources-url,omitempty"`
+	ResourcesSHA256 string `json:"resources-sha256,omitempty"`
+}
+
+// NewLock locks the releases of the catalog fetched from the external repositories, the
+// resources tarballs being downloaded to compute their SHA256.
+func NewLock(ctx context.Context, e config.External, c Catalog, opts Options) (Lock, error) {
+	l := Lock{}
+	for _, r := range e.Repositories {
+		name := r.Name
+		if name == "" {
+			name = filepath.Base(r.URL)
+		}
+		repository := LockedRepository{Name: name, URL: r.URL, Versions: []LockedRelease{}}
+		versions := make([]string, 0, len(c.Repositories[name]))
+		for version := range c.Repositories[name] {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versionLess(versions[i], versions[j]) })
+		for _, version := range versions {
+			release := c.Repositories[name][version]
+			repository.Versions = append(repository.Versions, LockedRelease{
+				Version:        version,
+				Tag:            release.Version.TagName,
+				ContractURL:    release.ContractURI,
+				ContractSHA256: release.ContractDigest,
+			})
+		}
+		l.Repositories = append(l.Repositories, repository)
+	}
+
+	type job struct{ repository, version int }
+	jobs := []job{}
+	for i, r := range l.Repositories {
+		for j := range r.Versions {
+			jobs = append(jobs, job{i, j})
+		}
+	}
+	errs := make([]error, len(jobs))
+	forEach(len(jobs), opts.Concurrency, func(i int) {
+		r := &l.Repositories[jobs[i].repository]
+		locked := &r.Versions[jobs[i].version]
+		release := c.Repositories[r.Name][locked.Version]
+		sum, err := resourcesSHA256(ctx, release, opts)
+		if errors.Is(err, fetcher.ErrResourcesNotFound) {
+			return
+		}
+		if err != nil {
+			errs[i] = fmt.Errorf("could not lock %s %s: %w", r.Name, locked.Version, err)
+			return
+		}
+		locked.ResourcesURL = release.ResourcesURI
+		locked.ResourcesSHA256 = sum
+	})
+	return l, errors.Join(errs...)
+}
+
+// resourcesSHA256 downloads the resources tarball of the release to compute its SHA256.
+func resourcesSHA256(ctx context.Context, release Release, opts Options) (string, error) {
+	body, err := openResources(ctx, release, opts.Cache)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, body); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// LoadLock loads the lock from the given file.
+func LoadLock(filename string) (Lock, error) {
+	var l Lock
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return Lock{}, fmt.Errorf("could not load lock from %s: %w", filename, err)
+	}
+	if err := yaml.Unmarshal(data, &l); err != nil {
+		return Lock{}, fmt.Errorf("could not load lock from %s: %w", filename, err)
+	}
+	return l, nil
+}
+
+// Save writes the lock in the given file.
+func (l Lock) Save(filename string) error {
+	data, err := yaml.Marshal(l)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, 0o644) // nolint:gosec
+}
+
+// repository returns the locked repository with the given name.
+func (l Lock) repository(name string) (LockedRepository, bool) {
+	for _, r := range l.Repositories {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return LockedRepository{}, false
+}
+
+// Sources restricts the sources to the locked releases: only the locked tags are listed, a
+// locked tag no longer listed or a repository not locked being a drift.
+func (l Lock) Sources(sources fetcher.SourceFunc) fetcher.SourceFunc {
+	return func(r config.Repository) (fetcher.Source, error) {
+		locked, ok := l.repository(r.Name)
+		if !ok || locked.URL != r.URL {
+			return nil, fmt.Errorf("%w: %s (%s) is not locked", ErrLockDrift, r.Name, r.URL)
+		}
+		s, err := sources(r)
+		if err != nil {
+			return nil, err
+		}
+		return lockedSource{Source: s, locked: locked}, nil
+	}
+}
+
+// lockedSource lists the locked releases only.
+type lockedSource struct {
+	fetcher.Source
+	locked LockedRepository
+}
+
+// OpenAsset opens the named file published with the version by the underlying source.
+func (s lockedSource) OpenAsset(ctx context.Context, v fetcher.Version, name string) (io.ReadCloser, error) {
+	return fetcher.OpenAsset(ctx, s.Source, v, name)
+}
+
+// ListVersions lists the locked versions, failing if any of them is no longer released.
+func (s lockedSource) ListVersions(ctx context.Context) ([]fetcher.Version, error) {
+	versions, err := s.Source.ListVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tags := make([]string, 0, len(s.locked.Versions))
+	for _, v := range s.locked.Versions {
+		tags = append(tags, v.Tag)
+	}
+	listed := []fetcher.Version{}
+	for _, v := range versions {
+		if i := slices.Index(tags, v.TagName); i >= 0 {
+			listed = append(listed, v)
+			tags = slices.Delete(tags, i, i+1)
+		}
+	}
+	if len(tags) > 0 {
+		return nil, fmt.Errorf("%w: locked tags %v of %s are not released", ErrLockDrift, tags, s.locked.URL)
+	}
+	return listed, nil
+}
+
+// Enforce checks the catalog holds exactly the locked releases, with the same contracts, and
+// pins the resources tarballs to their locked SHA256. A locked repository not fetched, removed
+// from the configuration or filtered out, is a drift too.
+func (l Lock) Enforce(c Catalog) error {
+	errs := []error{}
+	for name, repository := range c.Repositories {
+		locked, ok := l.repository(name)
+		if !ok {
+			errs = append(errs, fmt.Errorf("%w: %s is not locked", ErrLockDrift, name))
+			continue
+		}
+		for _, v := range locked.Versions {
+			release, ok := repository[v.Version]
+			switch {
+			case !ok:
+				errs = append(errs, fmt.Errorf("%w: %s %s is not fetched", ErrLockDrift, name, v.Version))
+				continue
+			case release.ContractURI != v.ContractURL:
+				errs = append(errs, fmt.Errorf("%w: %s %s contract moved from %s to %s", ErrLockDrift, name, v.Version, v.ContractURL, release.ContractURI))
+			case release.ContractDigest != v.ContractSHA256:
+				errs = append(errs, fmt.Errorf("%w: %s %s contract sha256 is %s, locked %s", ErrLockDrift, name, v.Version, release.ContractDigest, v.ContractSHA256))
+			case release.ResourcesURI != v.ResourcesURL && v.ResourcesURL != "":
+				errs = append(errs, fmt.Errorf("%w: %s %s resources moved from %s to %s", ErrLockDrift, name, v.Version, v.ResourcesURL, release.ResourcesURI))
+			}
+			release.ResourcesDigest = v.ResourcesSHA256
+			repository[v.Version] = release
+		}
+		if len(repository) != len(locked.Versions) {
+			errs = append(errs, fmt.Errorf("%w: %s has %d versions, %d locked", ErrLockDrift, name, len(repository), len(locked.Versions)))
+		}
+	}
+	for _, locked := range l.Repositories {
+		if _, ok := c.Repositories[locked.Name]; !ok {
+			errs = append(errs, fmt.Errorf("%w: %s (%s) is locked but not fetched", ErrLockDrift, locked.Name, locked.URL))
+		}
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errors.Join(errs...)
+}
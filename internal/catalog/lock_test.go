@@ -0,0 +1,118 @@
+package catalog_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/catalog"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestLock(t *testing.T) {
+	tmp := t.TempDir()
+	contract, err := os.ReadFile("testdata/catalog.simple.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	drifted := filepath.Join(tmp, "catalog.yaml")
+	if err := os.WriteFile(drifted, append(contract, []byte("# re-uploaded\n")...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	reuploaded := filepath.Join(tmp, "resources.tar.gz")
+	if err := os.WriteFile(reuploaded, []byte("re-uploaded"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	e := config.External{
+		Repositories: []config.Repository{{
+			Name:                 "sbr-golang",
+			URL:                  "https://fake.host/repo",
+			CatalogName:          "catalog.yaml",
+			ResourcesTarballName: "resources.tar.gz",
+		}},
+	}
+	source := fakeSource{
+		versions:  []fetcher.Version{{TagName: "v0.5.0"}},
+		contracts: map[string]string{"v0.5.0": "testdata/catalog.simple.yaml"},
+		resources: map[string]string{"v0.5.0": "testdata/resources.tar.gz"},
+	}
+	sources := func(_ config.Repository) (fetcher.Source, error) {
+		return source, nil
+	}
+	c, err := catalog.FetchFromExternals(context.Background(), e, sources, catalog.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := catalog.NewLock(context.Background(), e, c, catalog.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lockFile := filepath.Join(tmp, "externals.lock.yaml")
+	if err := l.Save(lockFile); err != nil {
+		t.Fatal(err)
+	}
+	if l, err = catalog.LoadLock(lockFile); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(l.Repositories), 1)
+	assert.Equal(t, len(l.Repositories[0].Versions), 1)
+	assert.Equal(t, l.Repositories[0].Versions[0].Tag, "v0.5.0")
+	assert.Assert(t, l.Repositories[0].Versions[0].ContractSHA256 != "")
+	assert.Assert(t, l.Repositories[0].Versions[0].ResourcesSHA256 != "")
+
+	// generate is a function as fakeSource is mutated below
+	generate := func() error {
+		c, err := catalog.FetchFromExternals(context.Background(), e, l.Sources(sources), catalog.Options{})
+		if err != nil {
+			return err
+		}
+		if err := l.Enforce(c); err != nil {
+			return err
+		}
+		dir := fs.NewDir(t, "catalog")
+		defer dir.Remove()
+		return catalog.GenerateFilesystem(context.Background(), dir.Path(), c, "", catalog.Options{})
+	}
+
+	t.Run("new release", func(t *testing.T) {
+		source.versions = []fetcher.Version{{TagName: "v0.6.0"}, {TagName: "v0.5.0"}}
+		source.contracts["v0.6.0"] = "testdata/catalog.simple.yaml"
+		assert.NilError(t, generate())
+	})
+	t.Run("release gone", func(t *testing.T) {
+		source.versions = []fetcher.Version{{TagName: "v0.6.0"}}
+		assert.Assert(t, errors.Is(generate(), catalog.ErrLockDrift))
+		source.versions = []fetcher.Version{{TagName: "v0.5.0"}}
+	})
+	t.Run("contract re-uploaded", func(t *testing.T) {
+		source.contracts["v0.5.0"] = drifted
+		assert.Assert(t, errors.Is(generate(), catalog.ErrLockDrift))
+		source.contracts["v0.5.0"] = "testdata/catalog.simple.yaml"
+	})
+	t.Run("resources re-uploaded", func(t *testing.T) {
+		source.resources["v0.5.0"] = reuploaded
+		assert.Assert(t, errors.Is(generate(), catalog.ErrLockDrift))
+		source.resources["v0.5.0"] = "testdata/resources.tar.gz"
+	})
+	t.Run("repository removed", func(t *testing.T) {
+		err := l.Enforce(catalog.Catalog{Repositories: map[string]catalog.Repository{}})
+		assert.Assert(t, errors.Is(err, catalog.ErrLockDrift))
+		assert.ErrorContains(t, err, "sbr-golang (https://fake.host/repo) is locked but not fetched")
+	})
+}
+
+func TestNewLockSortsVersions(t *testing.T) {
+	e := config.External{Repositories: []config.Repository{{Name: "sbr-golang", URL: "https://fake.host/repo"}}}
+	l, err := catalog.NewLock(context.Background(), e, layoutCatalog("0.9.0", "0.10.0"), catalog.Options{})
+	assert.NilError(t, err)
+	assert.Equal(t, len(l.Repositories), 1)
+	assert.Equal(t, len(l.Repositories[0].Versions), 2)
+	assert.Equal(t, l.Repositories[0].Versions[0].Version, "0.9.0")
+	assert.Equal(t, l.Repositories[0].Versions[1].Version, "0.10.0")
+}
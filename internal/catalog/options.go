@@ -0,0 +1,57 @@
+package catalog
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/cache"
+)
+
+// Options tunes how the catalog is fetched and generated.
+type Options struct {
+	// Concurrency bounds the number of repositories fetched, and releases extracted, in
+	// parallel. Anything below 1 means a serial run.
+	Concurrency int
+	// Cache keeps the resources tarballs across runs, nil to always download them.
+	Cache *cache.Cache
+	// Registry is the transport reaching the OCI registries the bundles are pulled from, the
+	// default one when nil.
+	Registry http.RoundTripper
+	// Incremental skips the releases already generated from the same checksums, and removes
+	// the generated resource versions no longer in the catalog.
+	Incremental bool
+	// MaxFileSize and MaxTotalSize cap the size of each file of a resources tarball, or each
+	// resource of the bundles, and of all of them, in bytes. DefaultMaxFileSize and DefaultMaxTotalSize are used when unset.
+	MaxFileSize  int64
+	MaxTotalSize int64
+	// Layout places the resources in the catalog, DefaultLayout when nil.
+	Layout Layout
+	// ArtifactHub writes the Artifact Hub metadata of the catalog, nil not to.
+	ArtifactHub *ArtifactHub
+	// Verify verifies the resource signatures of the repositories with a trust, with cosign
+	// when nil.
+	Verify VerifyFunc
+}
+
+// forEach calls fn for every index in [0, n), with at most concurrency calls running at once.
+func forEach(n, concurrency int, fn func(i int)) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < min(concurrency, n); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
@@ -0,0 +1,131 @@
+package catalog
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Action is what generating the catalog does to a resource version of the target.
+type Action string
+
+const (
+	// ActionAdd writes a resource version missing from the target.
+	ActionAdd Action = "add"
+	// ActionUpdate rewrites a resource version whose checksum changed.
+	ActionUpdate Action = "update"
+	// ActionRemove prunes a generated resource version no longer in the catalog.
+	ActionRemove Action = "remove"
+	// ActionNone leaves a resource version up to date.
+	ActionNone Action = ""
+)
+
+// PlanEntry is a resource version the catalog generation would change.
+type PlanEntry struct {
+	Action  Action `json:"action"`
+	Kind    string `json:"kind"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Source  string `json:"source,omitempty"`
+}
+
+// Plan lists what generating the catalog would change in the target, without fetching any
+// resources.
+type Plan struct {
+	// Entries are sorted by kind, name and version.
+	Entries []PlanEntry `json:"entries"`
+	// Unchanged counts the resource versions already up to date.
+	Unchanged int `json:"unchanged"`
+}
+
+// NewPlan compares the releases of the catalog with the resources already generated in path,
+// only the contracts being used. The generated resource versions no longer in the catalog are
+// planned for removal in incremental mode only, as GenerateFilesystem prunes them then only.
+func NewPlan(path string, c Catalog, resourceType string, opts Options) (Plan, error) {
+	p := Plan{Entries: []PlanEntry{}}
+	placer := newPlacer(path, opts.Layout)
+	jobs := catalogJobs(c)
+	expected, err := placements(placer, jobs, resourceType, opts.ArtifactHub != nil)
+	if err != nil {
+		return p, err
+	}
+	for _, j := range jobs {
+		source := j.release.RepositoryURL
+		for filename, r := range getResourcesFromType(j.release, resourceType) {
+			action := resourceState(placer, j.version, filename, r)
+			if action == ActionNone {
+				p.Unchanged++
+				continue
+			}
+			p.Entries = append(p.Entries, planEntry(action, filepath.Dir(filename), j.version, source))
+		}
+	}
+	if opts.Incremental {
+		files, err := stale(path, resourceType, expected)
+		if err != nil {
+			return p, err
+		}
+		for _, file := range files {
+			resource, err := readResource(file)
+			if err != nil {
+				return p, err
+			}
+			annotations := resource.Metadata.Annotations
+			version := annotations[VersionAnnotation]
+			if version == "" {
+				// generated before the version annotation, with the default layout
+				version = filepath.Base(filepath.Dir(file))
+			}
+			folder := filepath.Join(resource.kindFolder(), resource.Metadata.Name)
+			p.Entries = append(p.Entries, planEntry(ActionRemove, folder, version, annotations[SourceAnnotation]))
+		}
+	}
+	sort.Slice(p.Entries, func(i, j int) bool {
+		a, b := p.Entries[i], p.Entries[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return versionLess(a.Version, b.Version)
+	})
+	return p, nil
+}
+
+// planEntry returns the entry of the resource version, its folder being "kind/name".
+func planEntry(action Action, folder, version, source string) PlanEntry {
+	return PlanEntry{
+		Action:  action,
+		Kind:    filepath.Dir(folder),
+		Name:    filepath.Base(folder),
+		Version: version,
+		Source:  source,
+	}
+}
+
+// String formats the plan as a diff of the target: "+" for the resource versions added, "~"
+// for the ones updated and "-" for the ones removed, followed by a summary.
+func (p Plan) String() string {
+	b := strings.Builder{}
+	s := summary{unchanged: p.Unchanged}
+	for _, e := range p.Entries {
+		sign := map[Action]string{ActionAdd: "+", ActionUpdate: "~", ActionRemove: "-"}[e.Action]
+		fmt.Fprintf(&b, "%s %s/%s/%s", sign, e.Kind, e.Name, e.Version)
+		if e.Source != "" {
+			fmt.Fprintf(&b, " (%s)", e.Source)
+		}
+		b.WriteString("\n")
+		switch e.Action {
+		case ActionAdd:
+			s.added++
+		case ActionUpdate:
+			s.updated++
+		case ActionRemove:
+			s.removed++
+		}
+	}
+	fmt.Fprintf(&b, "# Plan: %s\n", s)
+	return b.String()
+}
@@ -0,0 +1,97 @@
+package catalog_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/catalog"
+	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/golden"
+)
+
+func TestNewPlan(t *testing.T) {
+	dir := fs.NewDir(t, "catalog")
+	defer dir.Remove()
+
+	release := func(tag, checksum string) catalog.Release {
+		return catalog.Release{
+			RepositoryURL: "https://fake.host/repo",
+			ResourcesURI:  "https://fake.host/repo/resources.tar.gz",
+			Source: fakeSource{
+				resources: map[string]string{tag: "testdata/resources.tar.gz"},
+			},
+			Version: fetcher.Version{TagName: tag},
+			Catalog: contract.Catalog{
+				Resources: &contract.Resources{
+					Tasks: []*contract.TektonResource{{
+						Name:     "go-crane-image",
+						Filename: "tasks/go-crane-image/go-crane-image.yaml",
+						Checksum: checksum,
+					}},
+				},
+			},
+		}
+	}
+	const checksum = "9b1f8e2ecbb5795727de93a6b95bbed2a4f44871f0f0ded6a2d8a04b2283a2b9"
+	c := catalog.Catalog{Repositories: map[string]catalog.Repository{
+		"sbr-golang": {
+			"0.4.0": release("v0.4.0", checksum),
+			"0.5.0": release("v0.5.0", checksum),
+		},
+	}}
+	opts := catalog.Options{Incremental: true}
+	if err := catalog.GenerateFilesystem(context.Background(), dir.Path(), c, "tasks", opts); err != nil {
+		t.Fatal(err)
+	}
+	// A generated version, no longer in the catalog.
+	stale := dir.Join("tasks", "go-crane-image", "0.3.0", "go-crane-image.yaml")
+	if err := os.MkdirAll(filepath.Dir(stale), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	content := strings.Replace(string(golden.Get(t, "tasks/go-crane-image/go-crane-image.yaml")), catalog.VersionAnnotation+`: "0.5.0"`, catalog.VersionAnnotation+`: "0.3.0"`, 1)
+	if err := os.WriteFile(stale, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Repositories["sbr-golang"]["0.5.0"] = release("v0.5.0", "0000")
+	c.Repositories["sbr-golang"]["0.6.0"] = release("v0.6.0", checksum)
+	p, err := catalog.NewPlan(dir.Path(), c, "tasks", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := "https://fake.host/repo"
+	assert.DeepEqual(t, p, catalog.Plan{
+		Entries: []catalog.PlanEntry{
+			{Action: catalog.ActionRemove, Kind: "tasks", Name: "go-crane-image", Version: "0.3.0", Source: source},
+			{Action: catalog.ActionUpdate, Kind: "tasks", Name: "go-crane-image", Version: "0.5.0", Source: source},
+			{Action: catalog.ActionAdd, Kind: "tasks", Name: "go-crane-image", Version: "0.6.0", Source: source},
+		},
+		Unchanged: 1,
+	})
+	assert.Equal(t, p.String(), `- tasks/go-crane-image/0.3.0 (`+source+`)
+~ tasks/go-crane-image/0.5.0 (`+source+`)
++ tasks/go-crane-image/0.6.0 (`+source+`)
+# Plan: 1 added, 1 updated, 1 unchanged, 1 removed
+`)
+
+	// Nothing is written while planning.
+	_, err = os.Stat(dir.Join("tasks", "go-crane-image", "0.6.0"))
+	assert.Assert(t, os.IsNotExist(err))
+}
+
+func TestNewPlanSortsVersions(t *testing.T) {
+	dir := fs.NewDir(t, "catalog")
+	defer dir.Remove()
+
+	p, err := catalog.NewPlan(dir.Path(), layoutCatalog("0.9.0", "0.10.0"), "tasks", catalog.Options{})
+	assert.NilError(t, err)
+	assert.Equal(t, len(p.Entries), 2)
+	assert.Equal(t, p.Entries[0].Version, "0.9.0")
+	assert.Equal(t, p.Entries[1].Version, "0.10.0")
+}
@@ -0,0 +1,121 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/attestation"
+	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
+)
+
+// ErrSignature marks the resources rejected by the trust of their repository: unsigned, or
+// whose signature doesn't verify against the pinned public key.
+var ErrSignature = errors.New("signature rejected")
+
+// VerifyFunc verifies the signature of the resource against the public key, a cosign key
+// reference.
+type VerifyFunc func(ctx context.Context, publicKey string, resource, signature []byte) error
+
+// signatureVerifier verifies the resources of a release against the trust of its repository.
+type signatureVerifier struct {
+	release Release
+	trust   config.Trust
+	verify  VerifyFunc
+}
+
+// signatureVerifier returns the verifier of the resources of the release, nil when its
+// repository has no trust.
+func (o Options) signatureVerifier(release Release) *signatureVerifier {
+	if release.Trust == nil {
+		return nil
+	}
+	verify := o.Verify
+	if verify == nil {
+		verify = attestation.VerifyBlob
+	}
+	return &signatureVerifier{release: release, trust: *release.Trust, verify: verify}
+}
+
+// signatureFile returns the path of the signature file of the resource, the one set by the
+// contract or the resource file with the signature extension. The contract may hold the
+// signature itself instead, returned as payload.
+func signatureFile(r contract.TektonResource) (path string, payload []byte) {
+	switch {
+	case r.Signature == "":
+		return fmt.Sprintf("%s.%s", r.Filename, contract.SignatureExtension), nil
+	case strings.HasSuffix(r.Signature, "."+contract.SignatureExtension):
+		return r.Signature, nil
+	default:
+		return "", []byte(r.Signature)
+	}
+}
+
+// wants reports whether the tarball entry is the signature file of one of the resources.
+func (v *signatureVerifier) wants(name string, tektonResources map[string]contract.TektonResource) bool {
+	for _, r := range tektonResources {
+		if path, _ := signatureFile(r); path == name {
+			return true
+		}
+	}
+	return false
+}
+
+// check verifies the signature of each resource of files, read from the contract, from the
+// signatures of the tarball, or from the release asset of the same name. The resources are
+// rejected following the trust policy: unsigned ones unless allow-unsigned or warn, invalidly
+// signed ones unless warn, the others being logged.
+func (v *signatureVerifier) check(ctx context.Context, log io.Writer, files []extracted, signatures map[string][]byte) error {
+	if v == nil {
+		return nil
+	}
+	policy := v.trust.GetPolicy()
+	for _, f := range files {
+		if f.resource == nil {
+			continue
+		}
+		signature, err := v.signature(ctx, *f.resource, signatures)
+		switch {
+		case errors.Is(err, fetcher.ErrAssetNotFound):
+			if policy == config.PolicyEnforce {
+				return fmt.Errorf("%w: %s is not signed", ErrSignature, f.resource.Filename)
+			}
+			fmt.Fprintf(log, "### WARNING: %s is not signed, accepted by the %s policy\n", f.resource.Filename, policy)
+			continue
+		case err != nil:
+			return err
+		}
+		if err := v.verify(ctx, v.trust.PublicKey, f.data, signature); err != nil {
+			if policy != config.PolicyWarn {
+				return fmt.Errorf("%w: %s: %w", ErrSignature, f.resource.Filename, err)
+			}
+			fmt.Fprintf(log, "### WARNING: %s signature is invalid, accepted by the %s policy: %v\n", f.resource.Filename, policy, err)
+			continue
+		}
+		fmt.Fprintf(log, "🔏 %s\n", f.resource.Filename)
+	}
+	return nil
+}
+
+// signature returns the signature of the resource, it returns fetcher.ErrAssetNotFound when
+// the release has none.
+func (v *signatureVerifier) signature(ctx context.Context, r contract.TektonResource, signatures map[string][]byte) ([]byte, error) {
+	path, payload := signatureFile(r)
+	if payload != nil {
+		return payload, nil
+	}
+	if signature, ok := signatures[path]; ok {
+		return signature, nil
+	}
+	body, err := fetcher.OpenAsset(ctx, v.release.Source, v.release.Version, filepath.Base(path))
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(io.LimitReader(body, DefaultMaxFileSize))
+}
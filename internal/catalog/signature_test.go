@@ -0,0 +1,113 @@
+package catalog_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/catalog"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+// newSigner returns a function signing contents the way cosign does, with a new key whose
+// public key is written in dir.
+func newSigner(t *testing.T, dir string) (func(content string) string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NilError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.NilError(t, err)
+	publicKey := filepath.Join(dir, "cosign.pub")
+	assert.NilError(t, os.WriteFile(publicKey, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+	return func(content string) string {
+		digest := sha256.Sum256([]byte(content))
+		signature, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+		assert.NilError(t, err)
+		return base64.StdEncoding.EncodeToString(signature)
+	}, publicKey
+}
+
+func TestGenerateFilesystemSignatures(t *testing.T) {
+	dir := fs.NewDir(t, "signed")
+	defer dir.Remove()
+	sign, publicKey := newSigner(t, dir.Path())
+	task := file("tasks/foo/foo.yaml", craftedTask)
+
+	for _, tc := range []struct {
+		name      string
+		entries   []entry
+		assets    map[string]string
+		signature string
+		policy    string
+		err       string
+	}{{
+		name:    "signed in the tarball",
+		entries: []entry{task, file("tasks/foo/foo.yaml.sig", sign(craftedTask))},
+	}, {
+		name:    "signed in the tarball first",
+		entries: []entry{file("tasks/foo/foo.yaml.sig", sign(craftedTask)), task},
+	}, {
+		name:    "signed in the release assets",
+		entries: []entry{task},
+		assets:  map[string]string{"v0.1.0/foo.yaml.sig": sign(craftedTask)},
+	}, {
+		name:      "signed in the contract",
+		entries:   []entry{task},
+		signature: sign(craftedTask),
+	}, {
+		name:      "signature path in the contract",
+		entries:   []entry{task, file("signatures/foo.sig", sign(craftedTask))},
+		signature: "signatures/foo.sig",
+	}, {
+		name:    "unsigned",
+		entries: []entry{task},
+		err:     "signature rejected: tasks/foo/foo.yaml is not signed",
+	}, {
+		name:    "unsigned allowed",
+		entries: []entry{task},
+		policy:  config.PolicyAllowUnsigned,
+	}, {
+		name:    "invalid signature",
+		entries: []entry{task, file("tasks/foo/foo.yaml.sig", sign("kind: Task\n"))},
+		policy:  config.PolicyAllowUnsigned,
+		err:     "signature rejected: tasks/foo/foo.yaml: ",
+	}, {
+		name:    "invalid signature with warn policy",
+		entries: []entry{task, file("tasks/foo/foo.yaml.sig", sign("kind: Task\n"))},
+		policy:  config.PolicyWarn,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			target := filepath.Join(t.TempDir(), "catalog")
+			c := craftedCatalog(craftTarball(t, t.TempDir(), tc.entries))
+			release := c.Repositories["crafted"]["0.1.0"]
+			source := release.Source.(fakeSource)
+			source.assets = tc.assets
+			release.Source = source
+			release.Catalog.Resources.Tasks[0].Signature = tc.signature
+			release.Trust = &config.Trust{PublicKey: publicKey, Policy: tc.policy}
+			c.Repositories["crafted"]["0.1.0"] = release
+
+			err := catalog.GenerateFilesystem(context.Background(), target, c, "tasks", catalog.Options{})
+			_, serr := os.Stat(filepath.Join(target, "tasks", "foo", "0.1.0", "foo.yaml"))
+			if tc.err != "" {
+				assert.Assert(t, errors.Is(err, catalog.ErrSignature), "unexpected error: %v", err)
+				assert.ErrorContains(t, err, tc.err)
+				assert.Assert(t, os.IsNotExist(serr), "nothing should be written for a rejected version")
+				return
+			}
+			assert.NilError(t, err)
+			assert.NilError(t, serr)
+		})
+	}
+}
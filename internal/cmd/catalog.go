@@ -21,6 +21,7 @@ func CatalogCmd(cfg *config.Config) *cobra.Command {
 	catalogCmd.AddCommand(NewCatalogGenerateCmd(cfg))
 	catalogCmd.AddCommand(NewCatalogGenerateFromExternalCmd(cfg))
 	catalogCmd.AddCommand(NewCatalogExternalsCmd(cfg))
+	catalogCmd.AddCommand(NewCatalogLockCmd(cfg))
 
 	return catalogCmd
 }
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/openshift-pipelines/catalog-cd/internal/config"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher"
 	fc "github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
 	"github.com/spf13/cobra"
 )
@@ -32,13 +33,20 @@ type GitHubRunObject struct {
 	IgnoreVersions       string `json:"ignoreVersions"`
 	CatalogName          string `json:"catalog-name"`
 	ResourcesTarballName string `json:"resources-tarball-name"`
+	Provider             string `json:"provider,omitempty"`
+	MaxReleases          int    `json:"max-releases,omitempty"`
+	Versions             string `json:"versions,omitempty"`
+	LatestMinors         int    `json:"latest-minors,omitempty"`
+	LatestPatch          bool   `json:"latest-patch,omitempty"`
+	Channel              string `json:"channel,omitempty"`
+	TagPattern           string `json:"tag-pattern,omitempty"`
 }
 
 type GitHubMatrixObject struct {
 	Include []GitHubRunObject `json:"include"`
 }
 
-func runCatalogExternals(_ context.Context, cfg *config.Config, args []string, o externalsOptions) error {
+func runCatalogExternals(ctx context.Context, cfg *config.Config, args []string, o externalsOptions) error {
 	required := []string{
 		o.config,
 	}
@@ -80,12 +88,24 @@ func runCatalogExternals(_ context.Context, cfg *config.Config, args []string, o
 				IgnoreVersions:       ignoreVersions,
 				CatalogName:          repository.CatalogName,
 				ResourcesTarballName: repository.ResourcesTarballName,
+				Provider:             repository.Provider,
+				MaxReleases:          repository.MaxReleases,
+				Versions:             repository.Versions.Constraint,
+				LatestMinors:         repository.Versions.LatestMinors,
+				LatestPatch:          repository.Versions.LatestPatch,
+				Channel:              repository.Channel,
+				TagPattern:           repository.TagPattern,
 			}
 			m.Include = append(m.Include, o)
 		}
 	}
 
-	if err = verifyNameConflicts(m); err != nil {
+	clients, release, err := cfg.Clients()
+	if err != nil {
+		return err
+	}
+	defer release()
+	if err = verifyNameConflicts(ctx, m, fetcher.DefaultSources(clients)); err != nil {
 		return err
 	}
 
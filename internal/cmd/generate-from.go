@@ -6,23 +6,33 @@ import (
 	"path"
 	"strings"
 
-	"github.com/cli/go-gh/v2/pkg/api"
 	"github.com/openshift-pipelines/catalog-cd/internal/catalog"
 	"github.com/openshift-pipelines/catalog-cd/internal/config"
 	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher"
 	fc "github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
 	"github.com/spf13/cobra"
 )
 
 // generateFromExternalOptions represents the "generate" subcommand to generate the signature of a resource file.
 type generateFromExternalOptions struct {
-	name                string // name of the repository to pull (a bit useless)
-	url                 string // url of the repository to pull
-	resourceType        string // type of resource to pull
-	ignoreVersions      string // versions to ignore while pulling
-	target              string // path to the folder where we want to generate the catalog
-	catalogName         string // name of the contract file to pull (default catalog.yaml)
-	resourceTarballName string // name of the resources file to pull (default resources.tar.gz)
+	name                string      // name of the repository to pull (a bit useless)
+	url                 string      // url of the repository to pull
+	provider            string      // provider of the repository to pull (github, gitlab, local, git, oci)
+	resourceType        string      // type of resource to pull
+	ignoreVersions      string      // versions to ignore while pulling
+	target              string      // path to the folder where we want to generate the catalog
+	catalogName         string      // name of the contract file to pull (default catalog.yaml)
+	resourceTarballName string      // name of the resources file to pull (default resources.tar.gz)
+	maxReleases         int         // maximum number of releases to pull, newest first (0 for all)
+	concurrency         int         // number of releases extracted in parallel
+	versions            fc.Versions // policy selecting the versions to pull
+	channel             string      // channel of the releases to pull (stable, preview, draft)
+	tagPattern          string      // pattern of the release tags, capturing the version
+	strict              bool        // fail when any version is skipped
+	failures            string      // path of the JSON file listing the versions skipped
+	layout              string      // layout of the catalog: default, tektoncd, flat or a Go template
+	trust               fc.Trust    // public key the resources must be signed with, and its policy
 }
 
 const generateLongFromExternalDescription = `# catalog-cd generate-partial
@@ -35,7 +45,7 @@ Generates a partial file-based catalog in the target folder, based of a set of f
       /path/to/catalog/target
 `
 
-func runGenerateFromExternal(_ context.Context, cfg *config.Config, args []string, o generateFromExternalOptions) error {
+func runGenerateFromExternal(ctx context.Context, cfg *config.Config, args []string, o generateFromExternalOptions) error {
 	if o.url == "" {
 		return fmt.Errorf("flag --config is required")
 	}
@@ -48,10 +58,35 @@ func runGenerateFromExternal(_ context.Context, cfg *config.Config, args []strin
 	}
 	o.target = args[0]
 	cfg.Infof("Generating a partial catalog from %s (type: %s)\n", o.url, o.resourceType)
-	ghclient, err := api.DefaultRESTClient()
+	if err := o.versions.Validate(); err != nil {
+		return err
+	}
+	if err := fc.ValidateChannel(o.channel); err != nil {
+		return err
+	}
+	if err := fc.ValidateTagPattern(o.tagPattern); err != nil {
+		return err
+	}
+	if err := (fc.Repository{URL: o.url, Provider: o.provider}).ValidateURL(); err != nil {
+		return err
+	}
+	var trust *fc.Trust
+	if o.trust.PublicKey != "" {
+		if err := o.trust.Validate(); err != nil {
+			return err
+		}
+		trust = &o.trust
+	}
+	layout, err := catalog.ParseLayout(o.layout)
+	if err != nil {
+		return err
+	}
+	clients, release, err := cfg.Clients()
 	if err != nil {
 		return err
 	}
+	defer release()
+	opts := catalog.Options{Concurrency: o.concurrency, Cache: cfg.Cache(), Registry: clients.Registry, Layout: layout}
 
 	name := o.name
 	if name == "" {
@@ -66,17 +101,24 @@ func runGenerateFromExternal(_ context.Context, cfg *config.Config, args []strin
 		Repositories: []fc.Repository{{
 			Name:                 name,
 			URL:                  o.url,
+			Provider:             o.provider,
 			IgnoreVersions:       ignoreVersions,
 			CatalogName:          o.catalogName,
 			ResourcesTarballName: o.resourceTarballName,
+			MaxReleases:          o.maxReleases,
+			Versions:             o.versions,
+			Channel:              o.channel,
+			TagPattern:           o.tagPattern,
+			Trust:                trust,
 		}},
 	}
-	c, err := catalog.FetchFromExternals(e, ghclient)
+	c, err := catalog.FetchFromExternals(ctx, e, fetcher.DefaultSources(clients), opts)
 	if err != nil {
 		return err
 	}
 
-	return catalog.GenerateFilesystem(o.target, c, o.resourceType)
+	err = catalog.GenerateFilesystem(ctx, o.target, c, o.resourceType, opts)
+	return reportFailures(cfg, err, o.strict, o.failures)
 }
 
 // NewCatalogGenerateFromExternalCmd instantiates the "generate" subcommand.
@@ -95,10 +137,23 @@ func NewCatalogGenerateFromExternalCmd(cfg *config.Config) *cobra.Command {
 
 	cmd.PersistentFlags().StringVar(&o.name, "name", "", "name of the repository to pull")
 	cmd.PersistentFlags().StringVar(&o.url, "url", "", "url of the repository to pull")
+	cmd.PersistentFlags().StringVar(&o.provider, "provider", "", "provider of the repository to pull (github, gitlab, local, git, oci), guessed from the url by default")
 	cmd.PersistentFlags().StringVar(&o.resourceType, "type", "", "type of resource to pull")
 	cmd.PersistentFlags().StringVar(&o.ignoreVersions, "ignore-versions", "", "versions to ignore while pulling")
 	cmd.PersistentFlags().StringVar(&o.catalogName, "catalog-name", contract.Filename, "contract name to pull")
 	cmd.PersistentFlags().StringVar(&o.resourceTarballName, "resource-tarball-name", contract.ResourcesName, "resource file to pull")
+	cmd.PersistentFlags().StringVar(&o.channel, "channel", "", "channel of the releases to pull (stable, preview, draft), stable by default")
+	cmd.PersistentFlags().StringVar(&o.tagPattern, "tag-pattern", "", "regular expression the release tags must match, with a \"version\" named group capturing the version")
+	cmd.PersistentFlags().IntVar(&o.concurrency, "concurrency", 4, "number of releases fetched and extracted in parallel")
+	cmd.PersistentFlags().BoolVar(&o.strict, "strict", inCI(), "fail when any version is skipped, the default when the CI environment variable is set")
+	cmd.PersistentFlags().StringVar(&o.failures, "failures", "", "path of the JSON file listing the versions skipped")
+	cmd.PersistentFlags().StringVar(&o.trust.PublicKey, "public-key", "", "public key the resources must be signed with, their signatures are not verified when not set")
+	cmd.PersistentFlags().StringVar(&o.trust.Policy, "trust-policy", fc.PolicyEnforce, "what to do with the unsigned or invalidly signed resources (enforce, allow-unsigned, warn)")
+	cmd.PersistentFlags().StringVar(&o.layout, "layout", "default", "layout of the catalog (default, tektoncd, flat) or Go template of the resource paths")
+	cmd.PersistentFlags().IntVar(&o.maxReleases, "max-releases", 0, "maximum number of releases to pull, newest first (0 for all)")
+	cmd.PersistentFlags().StringVar(&o.versions.Constraint, "versions", "", "semver range of the versions to pull (e.g. \">=0.3.0 <2.0.0\")")
+	cmd.PersistentFlags().IntVar(&o.versions.LatestMinors, "latest-minors", 0, "pull the versions of the N latest minor versions only (0 for all)")
+	cmd.PersistentFlags().BoolVar(&o.versions.LatestPatch, "latest-patch", false, "pull the latest patch version of each minor version only")
 
 	return cmd
 }
@@ -2,20 +2,37 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"strconv"
 
-	"github.com/cli/go-gh/v2/pkg/api"
 	"github.com/openshift-pipelines/catalog-cd/internal/catalog"
 	"github.com/openshift-pipelines/catalog-cd/internal/config"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher"
 	fc "github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
 	"github.com/spf13/cobra"
 )
 
 // generateOptions represents the "generate" subcommand to generate the signature of a resource file.
 type generateOptions struct {
-	config string // path for the catalog configuration file
-	target string // path to the folder where we want to generate the catalog
+	config      string   // path for the catalog configuration file
+	target      string   // path to the folder where we want to generate the catalog
+	concurrency int      // number of repositories and releases fetched in parallel
+	channel     string   // channel of all the repositories, overriding the configuration
+	mirrorRoot  string   // local directory mirroring the repositories, overriding the configuration
+	locked      bool     // generate the releases of the lock file only
+	lockFile    string   // path of the lock file
+	incremental bool     // skip the releases up to date and prune the ones no longer selected
+	strict      bool     // fail when any version is skipped
+	failures    string   // path of the JSON file listing the versions skipped
+	dryRun      bool     // print the plan of the generation instead of generating
+	output      string   // format of the plan: text or json
+	layout      string   // layout of the catalog: default, tektoncd, flat or a Go template
+	artifactHub bool     // write the Artifact Hub metadata along the catalog
+	ahRepoID    string   // Artifact Hub repository ID
+	ahOwners    []string // emails of the Artifact Hub repository owners
 }
 
 const generateLongDescription = `# catalog-cd generate
@@ -25,9 +42,48 @@ Generates a file-based catalog in the target folder, based of a configuration fi
   $ catalog-cd generate \
       --config="/path/to/external.yaml" \
       /path/to/catalog/target
+
+The resources are laid out as <kind>/<name>/<version>/<file> by default. With
+--layout=tektoncd, they follow the tektoncd/catalog layout <kind>/<name>/<major.minor>/<file>,
+the kind being singular; --layout=flat writes <name>-<version>.yaml files at the root. Any
+other layout is a Go template of the path, given the Kind, Name, Version, MajorMinor and File
+of each file, for instance --layout='{{.Kind}}/{{.Name}}-{{.Version}}/{{.File}}'.
+
+Repositories are local directories holding a folder per tag, with the contract and the
+resources tarball, when their URL is a file:// one or their provider is local. With
+--mirror-root (or mirror-root in the configuration), every remote repository is read from the
+<host>/<path> folder of its URL in the mirror instead, e.g. <mirror-root>/github.com/org/repo.
+
+With --dry-run, nothing is written: the versions that would be added (+), updated (~) or
+removed (-) are printed instead, as JSON with --output=json. Only the contracts are fetched.
+
+With --artifact-hub, the Artifact Hub metadata is written along the resources, for the
+catalog to be listed as a Tekton repository: artifacthub-pkg.yml in each resource version
+folder, and artifacthub-repo.yml at the root when a repository ID or owners are given.
+
+The resources of the repositories with a trust are verified against its pinned public key
+before being written, their signature being taken from the contract, the resources tarball or
+the release asset named after the resource with a .sig extension:
+
+  repositories:
+  - url: https://github.com/openshift-pipelines/task-containers
+    trust:
+      public-key: keys/task-containers.pub
+      policy: enforce
+
+The enforce policy rejects the versions with unsigned or invalidly signed resources,
+allow-unsigned accepts the unsigned ones, and warn accepts both with a warning. A relative
+public-key is a file next to the configuration file.
+
+With --incremental, the versions already generated from the same contract checksums are
+skipped, and the generated versions no longer selected by the configuration are removed.
+
+Versions failing to be fetched or extracted are skipped, with --strict (the default when the
+CI environment variable is set) the command then fails. The versions skipped are listed as
+JSON in the --failures file.
 `
 
-func runGenerate(_ context.Context, cfg *config.Config, args []string, o generateOptions) error {
+func runGenerate(ctx context.Context, cfg *config.Config, args []string, o generateOptions) error {
 	if o.config == "" {
 		return fmt.Errorf("flag --config is required")
 	}
@@ -44,22 +100,131 @@ func runGenerate(_ context.Context, cfg *config.Config, args []string, o generat
 			return err
 		}
 	}
-	cfg.Infof("Generating a catalog from %s in %s\n", o.config, o.target)
-	ghclient, err := api.DefaultRESTClient()
+	if o.output != "text" && o.output != "json" {
+		return fmt.Errorf("unsupported output %q, text or json expected", o.output)
+	}
+	if o.dryRun {
+		cfg.Errorf("# Planning a catalog from %s in %s\n", o.config, o.target)
+	} else {
+		cfg.Infof("Generating a catalog from %s in %s\n", o.config, o.target)
+	}
+	layout, err := catalog.ParseLayout(o.layout)
 	if err != nil {
 		return err
 	}
+	clients, release, err := cfg.Clients()
+	if err != nil {
+		return err
+	}
+	defer release()
+	opts := catalog.Options{
+		Concurrency: o.concurrency,
+		Cache:       cfg.Cache(),
+		Registry:    clients.Registry,
+		Incremental: o.incremental,
+		Layout:      layout,
+	}
+	if o.artifactHub {
+		opts.ArtifactHub = &catalog.ArtifactHub{RepositoryID: o.ahRepoID, Owners: o.ahOwners}
+	}
 
 	e, err := fc.LoadExternal(o.config)
 	if err != nil {
 		return err
 	}
-	c, err := catalog.FetchFromExternals(e, ghclient)
+	if o.channel != "" {
+		if err := fc.ValidateChannel(o.channel); err != nil {
+			return err
+		}
+		for i := range e.Repositories {
+			e.Repositories[i].Channel = o.channel
+		}
+	}
+	if o.mirrorRoot != "" {
+		e = e.Mirrored(o.mirrorRoot)
+	}
+	sources := fetcher.DefaultSources(clients)
+	var l catalog.Lock
+	if o.locked {
+		if o.lockFile == "" {
+			o.lockFile = lockFile(o.config)
+		}
+		if l, err = catalog.LoadLock(o.lockFile); err != nil {
+			return err
+		}
+		sources = l.Sources(sources)
+	}
+	c, err := catalog.FetchFromExternals(ctx, e, sources, opts)
+	if err != nil {
+		return err
+	}
+	if o.locked {
+		if err := l.Enforce(c); err != nil {
+			return err
+		}
+	}
+
+	if o.dryRun {
+		return printPlan(cfg, o.target, c, "", opts, o.output)
+	}
+
+	err = catalog.GenerateFilesystem(ctx, o.target, c, "", opts)
+	return reportFailures(cfg, err, o.strict, o.failures)
+}
+
+// printPlan prints what generating the catalog would change in the target, as a diff or as
+// JSON.
+func printPlan(cfg *config.Config, target string, c catalog.Catalog, resourceType string, opts catalog.Options, output string) error {
+	p, err := catalog.NewPlan(target, c, resourceType, opts)
 	if err != nil {
 		return err
 	}
+	if output == "json" {
+		data, err := json.MarshalIndent(p, "", "  ")
+		if err != nil {
+			return err
+		}
+		cfg.Infof("%s\n", data)
+		return nil
+	}
+	cfg.Infof("%s", p)
+	return nil
+}
+
+// reportFailures handles the versions skipped by the catalog generation: they are listed in
+// the failures file, when set, and fail the command in strict mode. Lock drifts always do.
+func reportFailures(cfg *config.Config, err error, strict bool, failuresFile string) error {
+	var gerr *catalog.GenerateError
+	if err != nil && !errors.As(err, &gerr) {
+		return err
+	}
+	if failuresFile != "" {
+		failures := []catalog.Failure{}
+		if gerr != nil {
+			failures = gerr.Failures
+		}
+		data, merr := json.MarshalIndent(failures, "", "  ")
+		if merr != nil {
+			return merr
+		}
+		if werr := os.WriteFile(failuresFile, append(data, '\n'), 0o644); werr != nil { // nolint:gosec
+			return werr
+		}
+	}
+	if gerr == nil {
+		return nil
+	}
+	if strict || errors.Is(gerr, catalog.ErrLockDrift) {
+		return gerr
+	}
+	cfg.Errorf("# WARNING: %v\n", gerr)
+	return nil
+}
 
-	return catalog.GenerateFilesystem(o.target, c, "")
+// inCI reports whether the command runs in a CI environment, setting the CI variable.
+func inCI() bool {
+	ci, _ := strconv.ParseBool(os.Getenv("CI"))
+	return ci
 }
 
 // NewCatalogGenerateCmd instantiates the "generate" subcommand.
@@ -77,6 +242,20 @@ func NewCatalogGenerateCmd(cfg *config.Config) *cobra.Command {
 	}
 
 	cmd.PersistentFlags().StringVar(&o.config, "config", "./externals.yaml", "path of the catalog configuration file")
+	cmd.PersistentFlags().StringVar(&o.channel, "channel", "", "channel of the releases to pull (stable, preview, draft), overriding the configuration")
+	cmd.PersistentFlags().StringVar(&o.mirrorRoot, "mirror-root", "", "local directory mirroring the repositories as <host>/<path>/<tag>, overriding the configuration")
+	cmd.PersistentFlags().BoolVar(&o.locked, "locked", false, "generate the releases of the lock file only, failing on any drift")
+	cmd.PersistentFlags().StringVar(&o.lockFile, "lock-file", "", "path of the lock file, next to the configuration file by default")
+	cmd.PersistentFlags().IntVar(&o.concurrency, "concurrency", 4, "number of repositories and releases fetched in parallel")
+	cmd.PersistentFlags().BoolVar(&o.strict, "strict", inCI(), "fail when any version is skipped, the default when the CI environment variable is set")
+	cmd.PersistentFlags().StringVar(&o.failures, "failures", "", "path of the JSON file listing the versions skipped")
+	cmd.PersistentFlags().BoolVar(&o.dryRun, "dry-run", false, "print the versions that would be added, updated or removed, without writing anything")
+	cmd.PersistentFlags().StringVar(&o.output, "output", "text", "format of the --dry-run plan (text, json)")
+	cmd.PersistentFlags().StringVar(&o.layout, "layout", "default", "layout of the catalog (default, tektoncd, flat) or Go template of the resource paths")
+	cmd.PersistentFlags().BoolVar(&o.artifactHub, "artifact-hub", false, "write the Artifact Hub metadata of the resources and the repository")
+	cmd.PersistentFlags().StringVar(&o.ahRepoID, "artifact-hub-repository-id", "", "ID of the Artifact Hub repository, written in artifacthub-repo.yml")
+	cmd.PersistentFlags().StringSliceVar(&o.ahOwners, "artifact-hub-owner", nil, "email of an owner of the Artifact Hub repository, written in artifacthub-repo.yml")
+	cmd.PersistentFlags().BoolVar(&o.incremental, "incremental", false, "skip the versions already generated, and remove the generated versions no longer selected")
 
 	return cmd
 }
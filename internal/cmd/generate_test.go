@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gomega "github.com/onsi/gomega"
+	"github.com/openshift-pipelines/catalog-cd/internal/catalog"
+	"github.com/openshift-pipelines/catalog-cd/internal/config"
+)
+
+func TestReportFailures(t *testing.T) {
+	gerr := &catalog.GenerateError{Failures: []catalog.Failure{{
+		Repository:   "sbr-golang",
+		Version:      "0.5.0",
+		ResourcesURI: "https://fake.host/repo/resources.tar.gz",
+		Err:          errors.New("invalid checksum"),
+	}}}
+	drift := &catalog.GenerateError{Failures: []catalog.Failure{{
+		Repository: "sbr-golang",
+		Version:    "0.5.0",
+		Err:        catalog.ErrLockDrift,
+	}}}
+	for _, tc := range []struct {
+		name        string
+		err         error
+		strict      bool
+		expectError bool
+		expected    int // failures listed
+	}{
+		{name: "no failure", err: nil, strict: true, expected: 0},
+		{name: "skipped", err: gerr, strict: false, expected: 1},
+		{name: "strict", err: gerr, strict: true, expectError: true, expected: 1},
+		{name: "lock drift", err: drift, strict: false, expectError: true, expected: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			failures := filepath.Join(t.TempDir(), "failures.json")
+
+			err := reportFailures(config.NewConfig(), tc.err, tc.strict, failures)
+			if tc.expectError {
+				g.Expect(err).To(gomega.HaveOccurred())
+			} else {
+				g.Expect(err).ToNot(gomega.HaveOccurred())
+			}
+
+			data, err := os.ReadFile(failures)
+			g.Expect(err).ToNot(gomega.HaveOccurred())
+			listed := []map[string]string{}
+			g.Expect(json.Unmarshal(data, &listed)).To(gomega.Succeed())
+			g.Expect(listed).To(gomega.HaveLen(tc.expected))
+			if tc.expected > 0 {
+				g.Expect(listed[0]).To(gomega.HaveKeyWithValue("repository", "sbr-golang"))
+				g.Expect(listed[0]).To(gomega.HaveKeyWithValue("version", "0.5.0"))
+				g.Expect(listed[0]).To(gomega.HaveKey("error"))
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/catalog"
+	"github.com/openshift-pipelines/catalog-cd/internal/config"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher"
+	fc "github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
+	"github.com/spf13/cobra"
+)
+
+// lockOptions represents the "lock" subcommand to lock the releases of the external repositories.
+type lockOptions struct {
+	config      string // path for the catalog configuration file
+	lockFile    string // path of the lock file to write
+	concurrency int    // number of repositories and releases fetched in parallel
+}
+
+const lockLongDescription = `# catalog-cd lock
+
+Resolves every repository of a configuration file to exact tags, and records them along with
+the SHA256 of their contract and resources tarball in a lock file. The lock file is then used
+by "catalog generate --locked" to generate the very same catalog.
+
+  $ catalog-cd catalog lock \
+      --config="/path/to/externals.yaml"
+`
+
+// lockFile returns the default lock file of the configuration file, externals.lock.yaml for
+// externals.yaml.
+func lockFile(configFile string) string {
+	return strings.TrimSuffix(configFile, filepath.Ext(configFile)) + ".lock.yaml"
+}
+
+func runLock(ctx context.Context, cfg *config.Config, args []string, o lockOptions) error {
+	if o.config == "" {
+		return fmt.Errorf("flag --config is required")
+	}
+	if len(args) != 0 {
+		return fmt.Errorf("lock takes no argument")
+	}
+	if _, err := os.Stat(o.config); err != nil {
+		return err
+	}
+	if o.lockFile == "" {
+		o.lockFile = lockFile(o.config)
+	}
+	cfg.Infof("Locking the repositories of %s in %s\n", o.config, o.lockFile)
+	clients, release, err := cfg.Clients()
+	if err != nil {
+		return err
+	}
+	defer release()
+	opts := catalog.Options{Concurrency: o.concurrency, Cache: cfg.Cache(), Registry: clients.Registry}
+
+	e, err := fc.LoadExternal(o.config)
+	if err != nil {
+		return err
+	}
+	c, err := catalog.FetchFromExternals(ctx, e, fetcher.DefaultSources(clients), opts)
+	if err != nil {
+		return err
+	}
+	l, err := catalog.NewLock(ctx, e, c, opts)
+	if err != nil {
+		return err
+	}
+	return l.Save(o.lockFile)
+}
+
+// NewCatalogLockCmd instantiates the "lock" subcommand.
+func NewCatalogLockCmd(cfg *config.Config) *cobra.Command {
+	o := lockOptions{}
+	cmd := &cobra.Command{
+		Use:          "lock",
+		Args:         cobra.ExactArgs(0),
+		Long:         lockLongDescription,
+		Short:        "Locks the releases of the repositories of a configuration file.",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runLock(cmd.Context(), cfg, args, o)
+		},
+	}
+
+	cmd.PersistentFlags().StringVar(&o.config, "config", "./externals.yaml", "path of the catalog configuration file")
+	cmd.PersistentFlags().StringVar(&o.lockFile, "lock-file", "", "path of the lock file to write, next to the configuration file by default")
+	cmd.PersistentFlags().IntVar(&o.concurrency, "concurrency", 4, "number of repositories and releases fetched in parallel")
+
+	return cmd
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/openshift-pipelines/catalog-cd/internal/config"
 	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+	"github.com/openshift-pipelines/catalog-cd/internal/oci"
 	"github.com/openshift-pipelines/catalog-cd/internal/resource"
 	"github.com/spf13/cobra"
 )
@@ -24,6 +25,7 @@ type releaseOptions struct {
 	output        string   // output path, where the contract and tarball will be written
 	catalogName   string   // name for the catalog.yaml
 	resourcesName string   // name for the resources tarball containing names
+	push          string   // OCI repository to push the release to ("oci://registry/repository")
 }
 
 const releaseLongDescription = `# catalog-cd release
@@ -46,13 +48,21 @@ order to change its location see "--output" flag.
 
 It always require the "--version" flag specifying the common revision for all
 resources in scope.
+
+The release can also be published as an OCI artifact, tagged with the version, using
+the "--push" flag:
+
+  $ catalog-cd release --version="0.0.1" --push="oci://ghcr.io/org/catalog" *.yaml
 `
 
-func runRelease(_ context.Context, cfg *config.Config, args []string, o releaseOptions) error {
+func runRelease(ctx context.Context, cfg *config.Config, args []string, o releaseOptions) error {
 	// Ensure the output flag is informed before attempting to search files
 	if o.output == "" {
 		return fmt.Errorf("--output flag is not informed")
 	}
+	if o.push != "" && !strings.HasPrefix(o.push, oci.Scheme) {
+		return fmt.Errorf("--push flag must be an OCI repository (%s...): %s", oci.Scheme, o.push)
+	}
 
 	o.paths = args
 	if len(o.paths) == 0 {
@@ -111,7 +121,20 @@ func runRelease(_ context.Context, cfg *config.Config, args []string, o releaseO
 	// Create a tarball (without catalog.yaml)
 	tarball := filepath.Join(o.output, o.resourcesName)
 	fmt.Fprintf(cfg.Stream.Err, "# Creating tarball at %q\n", tarball)
-	return createTektonResourceArchive(tarball, o.catalogName, o.resourcesName, o.output)
+	if err := createTektonResourceArchive(tarball, o.catalogName, o.resourcesName, o.output); err != nil {
+		return err
+	}
+	if o.push == "" {
+		return nil
+	}
+
+	ref := fmt.Sprintf("%s:%s", strings.TrimPrefix(o.push, oci.Scheme), o.version)
+	t, err := cfg.Transport()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cfg.Stream.Err, "# Pushing release to %q\n", ref)
+	return oci.Push(ctx, ref, catalogPath, tarball, oci.Transport(t)...)
 }
 
 // NewReleaseCmd instantiates the NewReleaseCmd subcommand and flags.
@@ -132,6 +155,7 @@ func NewReleaseCmd(cfg *config.Config) *cobra.Command {
 	cmd.PersistentFlags().StringVar(&o.output, "output", ".", "path to the release files (to attach to a given release)")
 	cmd.PersistentFlags().StringVar(&o.catalogName, "catalog-name", contract.Filename, "name for the catalog.yaml file")
 	cmd.PersistentFlags().StringVar(&o.resourcesName, "resources-tarball-name", contract.ResourcesName, "name for the catalog.yaml file")
+	cmd.PersistentFlags().StringVar(&o.push, "push", "", "OCI repository to push the release to (oci://registry/repository)")
 
 	if err := cmd.MarkPersistentFlagRequired("version"); err != nil {
 		panic(err)
@@ -1,9 +1,6 @@
 package cmd
 
 import (
-	"context"
-	"net/http"
-
 	"github.com/openshift-pipelines/catalog-cd/internal/contract"
 )
 
@@ -16,12 +13,3 @@ func LoadContractFromArgs(args []string) (*contract.Contract, error) {
 	}
 	return contract.NewContractFromFile(location)
 }
-
-func MakeGetRequest(uri string) (*http.Response, error) {
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, uri, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	return http.DefaultClient.Do(req)
-}
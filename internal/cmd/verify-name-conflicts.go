@@ -1,32 +1,16 @@
 package cmd
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
-	"net/http"
-	"os"
-	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/openshift-pipelines/catalog-cd/internal/contract"
-	"gopkg.in/yaml.v2"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher"
+	fc "github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
 )
 
-// GitHubTags represents the response JSON when fetching various tags/releases of a GitHub repo.
-type GitHubTags struct {
-	TagName string         `json:"tag_name"`
-	URL     string         `json:"url"`
-	Assets  []GitHubAssets `json:"assets"`
-}
-
-// GitHubAssets represents the Assets field of the GitHubTags.
-type GitHubAssets struct {
-	Name               string `json:"name"`
-	BrowserDownloadURL string `json:"browser_download_url"`
-	URL                string `json:"url"`
-}
-
 // ResourceInfo represents the value field of a map where the key is the name of the resource.
 type ResourceInfo struct {
 	Source  string
@@ -35,56 +19,38 @@ type ResourceInfo struct {
 
 // verifyNameConflicts function handles the logic to fetch the various releases from the repos & check whether they have any conflicts in their Name,
 // either from the same repo. i.e. same source or from different repo. i.e. different source.
-func verifyNameConflicts(m GitHubMatrixObject) error {
-	tempDirPath, err := os.MkdirTemp("", "example")
-	if err != nil {
-		return err
-	}
-	defer os.RemoveAll(tempDirPath)
-
+func verifyNameConflicts(ctx context.Context, m GitHubMatrixObject, sources fetcher.SourceFunc) error {
 	kindSourceMap := make(map[string]map[string][]ResourceInfo)
 	kindSourceMap["tasks"] = make(map[string][]ResourceInfo)
 	kindSourceMap["pipelines"] = make(map[string][]ResourceInfo)
 	kindSourceMap["stepactions"] = make(map[string][]ResourceInfo)
 
+	// Releases are listed once per repository selection, whatever the number of types pulled
+	// from it. The components of a monorepo are selections of the same URL.
+	repositoryReleases := make(map[releaseSelection]map[string]fetcher.Release)
 	for _, githubObj := range m.Include {
-		var orgURL string
-		partsOfURL := strings.Split(githubObj.URL, "github.com/")
-
-		if len(partsOfURL) > 1 {
-			orgURL = partsOfURL[1]
-		} else {
-			return fmt.Errorf("incorrect url i.e. url doesn't contain github.com")
-		}
-
-		tagsURL := "https://api.github.com/repos/" + orgURL + "/releases"
-		tagsResp, err := MakeGetRequest(tagsURL)
-		if err != nil {
-			return err
-		}
-		defer tagsResp.Body.Close()
-
-		tagsBody, err := io.ReadAll(tagsResp.Body)
-		if err != nil {
-			return err
+		r := githubObj.repository()
+		releases, ok := repositoryReleases[selection(r)]
+		if !ok {
+			s, err := sources(r)
+			if err != nil {
+				return err
+			}
+			releases, err = fetcher.FetchContractsFromRepository(ctx, r, s)
+			if err != nil {
+				return err
+			}
+			repositoryReleases[selection(r)] = releases
 		}
 
-		var releases []GitHubTags
-		if err = json.Unmarshal(tagsBody, &releases); err != nil {
-			return err
+		tags := make([]string, 0, len(releases))
+		for tag := range releases {
+			tags = append(tags, tag)
 		}
-
-		for _, release := range releases {
-			if !strings.Contains(githubObj.IgnoreVersions, release.TagName) {
-				for _, asset := range release.Assets {
-					if asset.Name == contract.Filename {
-						filePath := fmt.Sprintf("%s/%s-%s-%s.yaml", tempDirPath, githubObj.Type, release.TagName, githubObj.Name)
-						err := downloadAndParseFile(asset.BrowserDownloadURL, filePath, githubObj.Type, kindSourceMap)
-						if err != nil {
-							return err
-						}
-					}
-				}
+		sort.Strings(tags)
+		for _, tag := range tags {
+			if err := parseContract(releases[tag].Contract, githubObj.Type, kindSourceMap, r.URL); err != nil {
+				return err
 			}
 		}
 	}
@@ -92,51 +58,53 @@ func verifyNameConflicts(m GitHubMatrixObject) error {
 	return nil
 }
 
-// downloadAndParseFile function downloads the various catalog.yaml files of each release & then parses them.
-func downloadAndParseFile(url, filepath, kind string, unique map[string]map[string][]ResourceInfo) error {
-	if err := downloadFile(url, filepath); err != nil {
-		return err
-	}
-
-	if err := parseFile(filepath, kind, unique, url); err != nil {
-		return err
-	}
-
-	return nil
+// releaseSelection identifies the releases selected from a repository.
+type releaseSelection struct {
+	url, provider, tagPattern, channel string
+	ignoreVersions                     string // joined, to keep the selection comparable
+	versions                           fc.Versions
+	maxReleases                        int
 }
 
-// downloadFile function downloads the file mentioned in the url & stores it in the mentioned filepath.
-func downloadFile(url, filepath string) error {
-	file, err := os.OpenFile(filepath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
-	if err != nil {
-		return err
+// selection returns what selects the releases of the repository.
+func selection(r fc.Repository) releaseSelection {
+	return releaseSelection{
+		url:            r.URL,
+		provider:       r.Provider,
+		tagPattern:     r.TagPattern,
+		channel:        r.Channel,
+		ignoreVersions: strings.Join(r.IgnoreVersions, ","),
+		versions:       r.Versions,
+		maxReleases:    r.MaxReleases,
 	}
-	defer file.Close()
-
-	res, err := MakeGetRequest(url)
-	if err != nil {
-		return err
-	}
-	defer res.Body.Close()
+}
 
-	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("server returned non-200 status code: %v", res.Status)
+// repository returns the repository configuration the matrix entry pulls from.
+func (o GitHubRunObject) repository() fc.Repository {
+	ignoreVersions := []string{}
+	if o.IgnoreVersions != "" {
+		ignoreVersions = strings.Split(o.IgnoreVersions, ",")
 	}
-
-	if _, err = io.Copy(file, res.Body); err != nil {
-		return err
+	return fc.Repository{
+		Name:                 o.Name,
+		URL:                  o.URL,
+		Provider:             o.Provider,
+		IgnoreVersions:       ignoreVersions,
+		CatalogName:          o.CatalogName,
+		ResourcesTarballName: o.ResourcesTarballName,
+		MaxReleases:          o.MaxReleases,
+		Versions: fc.Versions{
+			Constraint:   o.Versions,
+			LatestMinors: o.LatestMinors,
+			LatestPatch:  o.LatestPatch,
+		},
+		Channel:    o.Channel,
+		TagPattern: o.TagPattern,
 	}
-
-	return nil
 }
 
 // parseResources function parses the resources & checks for uniqueness.
 func parseResources(resources []*contract.TektonResource, unique map[string][]ResourceInfo, source, kind string) error {
-	source1, err := extractBaseURL(source)
-	if err != nil {
-		return err
-	}
-
 	for _, res := range resources {
 		name := res.Name
 		version := res.Version
@@ -145,13 +113,8 @@ func parseResources(resources []*contract.TektonResource, unique map[string][]Re
 		if exists {
 			currResources := unique[name]
 
-			source2, err := extractBaseURL(currResources[0].Source)
-			if err != nil {
-				return err
-			}
-
 			// Checks whether the sources are different.
-			if source1 != source2 {
+			if currResources[0].Source != source {
 				return fmt.Errorf("two resources of kind '%s', have same name '%s', from different sources, \nsource1: %s\nsource2: %s", kind, name, currResources[0].Source, source)
 			}
 			// Checks whether the versions are same or not, if its from same source.
@@ -170,48 +133,20 @@ func parseResources(resources []*contract.TektonResource, unique map[string][]Re
 	return nil
 }
 
-// parseFile function parses the catalog file given in the path argument, gets the resources mentioned in this catalog file & then calls the parseResources function to check for uniqueness.
-func parseFile(path, kind string, unique map[string]map[string][]ResourceInfo, source string) error {
-	yamlFile, err := os.ReadFile(path)
-	if err != nil {
-		return err
-	}
-
-	var catalog contract.Contract
-
-	err = yaml.Unmarshal(yamlFile, &catalog)
-	if err != nil {
-		return err
-	}
-
+// parseContract function gets the resources of the given kind mentioned in the contract & then calls the parseResources function to check for uniqueness.
+func parseContract(c *contract.Contract, kind string, unique map[string]map[string][]ResourceInfo, source string) error {
 	var resources []*contract.TektonResource
 
 	switch kind {
 	case "tasks":
-		resources = catalog.Catalog.Resources.Tasks
+		resources = c.Catalog.Resources.Tasks
 	case "pipelines":
-		resources = catalog.Catalog.Resources.Pipelines
+		resources = c.Catalog.Resources.Pipelines
 	case "stepactions":
-		resources = catalog.Catalog.Resources.StepActions
+		resources = c.Catalog.Resources.StepActions
 	default:
 		return fmt.Errorf("kind is not tasks, pipelines or stepactions")
 	}
 
-	err = parseResources(resources, unique[kind], source, kind)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func extractBaseURL(url string) (string, error) {
-	re := regexp.MustCompile(`^(https://github\.com/[^/]+/[^/]+)`)
-
-	match := re.FindStringSubmatch(url)
-	if len(match) < 2 {
-		return "", fmt.Errorf("base URL not found in: %s", url)
-	}
-
-	return match[1], nil
+	return parseResources(resources, unique[kind], source, kind)
 }
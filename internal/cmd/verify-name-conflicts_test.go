@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	gomega "github.com/onsi/gomega"
+	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher"
+	fc "github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
+)
+
+// monorepoSource releases the git-clone and git-init tasks of a monorepo, each with its own
+// tags.
+type monorepoSource struct{}
+
+func (monorepoSource) ListVersions(_ context.Context) ([]fetcher.Version, error) {
+	return []fetcher.Version{{TagName: "git-clone/v0.1.0"}, {TagName: "git-init/v0.1.0"}}, nil
+}
+
+func (monorepoSource) FetchContract(_ context.Context, v fetcher.Version) (*contract.Contract, error) {
+	name, version, _ := strings.Cut(v.TagName, "/v")
+	c := contract.NewContractEmpty()
+	c.Catalog.Resources.Tasks = []*contract.TektonResource{{Name: name, Version: version}}
+	return c, nil
+}
+
+func (monorepoSource) OpenResources(_ context.Context, _ fetcher.Version) (io.ReadCloser, error) {
+	return nil, fetcher.ErrResourcesNotFound
+}
+
+func TestVerifyNameConflictsMonorepo(t *testing.T) {
+	g := gomega.NewWithT(t)
+	url := "https://github.com/org/monorepo"
+	m := GitHubMatrixObject{Include: []GitHubRunObject{
+		{Name: "git-clone", URL: url, Type: "tasks", TagPattern: "git-clone/(?P<version>.*)"},
+		{Name: "git-init", URL: url, Type: "tasks", TagPattern: "git-init/(?P<version>.*)"},
+	}}
+	selected := map[string]int{}
+	sources := func(r fc.Repository) (fetcher.Source, error) {
+		selected[r.TagPattern]++
+		return monorepoSource{}, nil
+	}
+
+	g.Expect(verifyNameConflicts(context.Background(), m, sources)).To(gomega.Succeed())
+	// Each component lists the releases of its own tags.
+	g.Expect(selected).To(gomega.Equal(map[string]int{
+		"git-clone/(?P<version>.*)": 1,
+		"git-init/(?P<version>.*)":  1,
+	}))
+}
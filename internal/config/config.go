@@ -1,9 +1,21 @@
 package config
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"sync"
 
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/cli/go-gh/v2/pkg/auth"
+	"github.com/openshift-pipelines/catalog-cd/internal/cache"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher"
+	"github.com/openshift-pipelines/catalog-cd/internal/transport"
 	"github.com/spf13/pflag"
 	tkncli "github.com/tektoncd/cli/pkg/cli"
 )
@@ -14,6 +26,10 @@ type Config struct {
 	kubeContext    string
 	namespace      string
 	tp             *tkncli.TektonParams
+	cacheDir       string
+	noCache        bool
+	caBundle       string
+	proxy          string
 }
 
 func (c *Config) Infof(format string, a ...any) {
@@ -46,6 +62,143 @@ func (c *Config) GetTektonParams() *tkncli.TektonParams {
 	return c.tp
 }
 
+// Cache returns the on-disk cache of the documents fetched from remote repositories, nil when
+// caching is disabled.
+func (c *Config) Cache() *cache.Cache {
+	if c.noCache || c.cacheDir == "" {
+		return nil
+	}
+	return cache.New(c.cacheDir)
+}
+
+// Clients returns the clients used to reach the remote repositories, the GitHub ones resolving
+// the API endpoint and credentials of each host from the gh environment, and the function
+// releasing them. The git repositories are mirrored in the cache directory, or in a temporary
+// directory removed on release when caching is disabled.
+func (c *Config) Clients() (fetcher.Clients, func(), error) {
+	base, err := c.Transport()
+	if err != nil {
+		return fetcher.Clients{}, nil, err
+	}
+	gitDir, release := filepath.Join(c.cacheDir, "git"), func() {}
+	if c.noCache || c.cacheDir == "" {
+		if gitDir, err = os.MkdirTemp("", "catalog-cd-git-"); err != nil {
+			return fetcher.Clients{}, nil, err
+		}
+		release = func() { _ = os.RemoveAll(gitDir) }
+	}
+	t := c.transport(base)
+	settings := []string{}
+	if c.caBundle != "" {
+		settings = append(settings, "http.sslCAInfo="+c.caBundle)
+	}
+	if c.proxy != "" {
+		settings = append(settings, "http.proxy="+c.proxy)
+	}
+	return fetcher.Clients{
+		GitHub:      gitHubClients(t, c.Stream.Err),
+		HTTP:        &http.Client{Transport: t},
+		Registry:    base,
+		GitSettings: settings,
+		GitDir:      gitDir,
+		Cache:       c.Cache(),
+	}, release, nil
+}
+
+// gitHubClients returns the GitHub clients of each host, created once and going through the
+// transport. Without a token for the host (GH_TOKEN, GITHUB_TOKEN or gh auth), the clients are
+// anonymous: public repositories are reachable, within the lower rate limits.
+func gitHubClients(t http.RoundTripper, log io.Writer) fetcher.GitHubClients {
+	type hostClients struct {
+		rest   *api.RESTClient
+		assets *http.Client
+	}
+	mu := sync.Mutex{}
+	hosts := map[string]hostClients{}
+	return func(host string) (*api.RESTClient, *http.Client, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if h, ok := hosts[host]; ok {
+			return h.rest, h.assets, nil
+		}
+		opts := api.ClientOptions{Host: host, Transport: t}
+		if opts.AuthToken, _ = auth.TokenForHost(host); opts.AuthToken == "" {
+			fmt.Fprintf(log, "# WARNING: no authentication token for %s, its API is reached anonymously\n", host)
+			// go-gh insists on a token, this one is stripped off the requests.
+			opts.AuthToken = anonymousToken
+			opts.Transport = anonymous{t}
+		}
+		rest, err := api.NewRESTClient(opts)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", host, err)
+		}
+		assets, err := api.NewHTTPClient(opts)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", host, err)
+		}
+		hosts[host] = hostClients{rest: rest, assets: assets}
+		return rest, assets, nil
+	}
+}
+
+// anonymousToken is the placeholder token of the anonymous GitHub clients.
+const anonymousToken = "anonymous"
+
+// anonymous strips the placeholder token off the requests of the anonymous GitHub clients.
+type anonymous struct {
+	rt http.RoundTripper
+}
+
+func (a anonymous) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt := a.rt
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	if req.Header.Get("Authorization") == "token "+anonymousToken {
+		req = req.Clone(req.Context())
+		req.Header.Del("Authorization")
+	}
+	return rt.RoundTrip(req)
+}
+
+// Transport returns the transport reaching the network, trusting the CA bundle on top of the
+// system certificates and going through the proxy (taken from the environment by default).
+func (c *Config) Transport() (http.RoundTripper, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if c.caBundle != "" {
+		data, err := os.ReadFile(c.caBundle)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no PEM certificate found in %s", c.caBundle)
+		}
+		t.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	if c.proxy != "" {
+		u, err := url.Parse(c.proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %q: %w", c.proxy, err)
+		}
+		t.Proxy = http.ProxyURL(u)
+	}
+	return t, nil
+}
+
+// transport wraps the base transport for the HTTP clients: it retries on transient failures
+// and rate limits, and revalidates the cached responses when caching is enabled.
+func (c *Config) transport(base http.RoundTripper) http.RoundTripper {
+	var t http.RoundTripper = transport.NewRetry(base, c.Stream.Err)
+	if cc := c.Cache(); cc != nil {
+		t = cc.Transport(t)
+	}
+	return t
+}
+
 func (c *Config) GetNamespace() string {
 	return c.GetTektonParams().Namespace()
 }
@@ -68,6 +221,17 @@ func NewConfigWithFlags(stream *tkncli.Stream, flags *pflag.FlagSet) *Config {
 		"kubernetes context name")
 	flags.StringVarP(&cfg.namespace, "namespace", "n", cfg.namespace,
 		"kubernetes namespace name")
+	if dir, err := os.UserCacheDir(); err == nil {
+		cfg.cacheDir = filepath.Join(dir, "catalog-cd")
+	}
+	flags.StringVar(&cfg.cacheDir, "cache-dir", cfg.cacheDir,
+		"directory caching the contracts, release listings and resources tarballs fetched")
+	flags.BoolVar(&cfg.noCache, "no-cache", cfg.noCache,
+		"always fetch from the remote repositories, bypassing the cache")
+	flags.StringVar(&cfg.caBundle, "ca-bundle", cfg.caBundle,
+		"PEM file of the certificate authorities to trust, on top of the system ones")
+	flags.StringVar(&cfg.proxy, "proxy", cfg.proxy,
+		"proxy URL to reach the network through, taken from HTTPS_PROXY and HTTP_PROXY by default")
 	return cfg
 }
 
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGitHubClientsAnonymous(t *testing.T) {
+	for _, env := range []string{"GH_TOKEN", "GITHUB_TOKEN", "GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"} {
+		t.Setenv(env, "")
+	}
+	t.Setenv("GH_CONFIG_DIR", t.TempDir())
+	t.Setenv("GH_PATH", "/nonexistent/gh")
+
+	authorization := []string{}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = append(authorization, r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	t.Cleanup(server.Close)
+
+	log := &strings.Builder{}
+	rest, _, err := gitHubClients(server.Client().Transport, log)(strings.TrimPrefix(server.URL, "https://"))
+	if err != nil {
+		t.Fatalf("Should have created anonymous clients, got %v", err)
+	}
+	var releases []any
+	if err := rest.Get("repos/shortbrain/golang-tasks/releases", &releases); err != nil {
+		t.Fatal(err)
+	}
+	if len(authorization) != 1 || authorization[0] != "" {
+		t.Fatalf("Should have sent no authorization, got %q", authorization)
+	}
+	if !strings.Contains(log.String(), "reached anonymously") {
+		t.Fatalf("Should have warned about the anonymous clients, got %q", log.String())
+	}
+}
@@ -23,6 +23,9 @@ type TektonResource struct {
 	// location to the signature file. By default, it uses the ".filename" attributed
 	// followed by ".sig" extension.
 	Signature string `json:"signature"`
+	// Bundle optional Tekton bundle reference holding the resource, pinned by digest. It's
+	// used when the release doesn't publish a resources tarball.
+	Bundle string `json:"bundle,omitempty"`
 }
 
 // Resources inventory of all Tekton resources managed by the repository.
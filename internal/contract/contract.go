@@ -2,9 +2,9 @@ package contract
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"path"
 
@@ -40,6 +40,7 @@ type Catalog struct {
 // Contract contains a versioned catalog.
 type Contract struct {
 	file    string  // contract file full path
+	digest  string  // SHA256 of the payload the contract was loaded from
 	Version string  `json:"version"` // contract version
 	Catalog Catalog `json:"catalog"` // tekton resources catalog
 }
@@ -55,6 +56,12 @@ func (c *Contract) Print() ([]byte, error) {
 	return b.Bytes(), nil
 }
 
+// Digest returns the hex encoded SHA256 of the payload the contract was loaded from, empty
+// when it wasn't loaded from a payload.
+func (c *Contract) Digest() string {
+	return c.digest
+}
+
 // Save saves the contract on the original file.
 func (c *Contract) Save() error {
 	if c.file == "" {
@@ -110,28 +117,13 @@ func NewContractFromFile(location string) (*Contract, error) {
 	return NewContractFromData(payload)
 }
 
-// NewContractFromURL instantiates a new Contract{} from a URL.
-func NewContractFromURL(url string) (*Contract, error) {
-	resp, err := http.Get(url) // nolint:gosec,noctx
-	if err != nil {
-		return nil, fmt.Errorf("could not load contract from %s: %w", url, err)
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("status error: %v", resp.StatusCode)
-	}
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("could not load contract from %s: %w", url, err)
-	}
-	return NewContractFromData(data)
-}
-
 // NewContractFromData instantiates a new Contract{} from a YAML payload.
 func NewContractFromData(payload []byte) (*Contract, error) {
 	c := Contract{}
 	if err := yaml.Unmarshal(payload, &c); err != nil {
 		return nil, err
 	}
+	sum := sha256.Sum256(payload)
+	c.digest = hex.EncodeToString(sum[:])
 	return &c, nil
 }
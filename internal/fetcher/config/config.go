@@ -3,14 +3,58 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/openshift-pipelines/catalog-cd/internal/contract"
 	"sigs.k8s.io/yaml"
 )
 
+const (
+	// ProviderGitHub fetches releases from a GitHub repository.
+	ProviderGitHub = "github"
+	// ProviderGitLab fetches releases from a GitLab project.
+	ProviderGitLab = "gitlab"
+	// ProviderLocal fetches releases from a local directory, holding one folder per version:
+	// a "file://" URL, a path with the provider set explicitly, or a mirror (see MirrorRoot).
+	ProviderLocal = "local"
+	// ProviderGit fetches releases from the tags of a plain git repository.
+	ProviderGit = "git"
+	// ProviderOCI fetches releases from the tags of an OCI repository ("oci://" URLs).
+	ProviderOCI = "oci"
+)
+
+const (
+	// ChannelStable pulls the published releases only, the default.
+	ChannelStable = "stable"
+	// ChannelPreview pulls the pre-releases too.
+	ChannelPreview = "preview"
+	// ChannelDraft pulls the pre-releases and the draft releases too.
+	ChannelDraft = "draft"
+)
+
+const (
+	// PolicyEnforce rejects the versions with unsigned or invalidly signed resources, the
+	// default.
+	PolicyEnforce = "enforce"
+	// PolicyAllowUnsigned accepts the unsigned resources, still rejecting the versions with
+	// invalidly signed ones.
+	PolicyAllowUnsigned = "allow-unsigned"
+	// PolicyWarn accepts all the resources, warning about the unsigned or invalidly signed ones.
+	PolicyWarn = "warn"
+)
+
 // External is a representation of the configuration for specifying repositories we have to pull from.
 type External struct {
+	// Channel is the default channel of the repositories (stable, preview, draft).
+	Channel string
+	// MirrorRoot is a local directory mirroring the repositories, relative to the
+	// configuration file. When set, the releases of each repository are read from the
+	// <host>/<path> folder of its URL, e.g. <mirror-root>/github.com/org/repo.
+	MirrorRoot string `json:"mirror-root"`
 	// Repositories defines the repositories to pull from
 	Repositories []Repository
 }
@@ -19,11 +63,115 @@ type External struct {
 type Repository struct {
 	Name string
 	URL  string
+	// Provider defines where releases are fetched from (github, gitlab, local, git, oci).
+	// When empty, it is guessed from the URL.
+	Provider string
 	// Type defines the type to fetch (Task, Pipeline, …)
-	Types                []string
+	Types []string
+	// IgnoreVersions lists the tags never pulled, left out before the versions policy applies.
 	IgnoreVersions       []string `json:"ignore-versions"`
 	CatalogName          string   `json:"catalog-name"`
 	ResourcesTarballName string   `json:"resources-tarball-name"`
+	// MaxReleases caps the number of releases listed from the repository, newest first.
+	// When 0, all the releases are listed.
+	MaxReleases int `json:"max-releases"`
+	// Versions selects the versions to pull among the released ones, all of them when not set.
+	Versions Versions `json:"versions"`
+	// Channel defines which kind of releases are pulled (stable, preview, draft), defaulting
+	// to the External one. When empty, only stable releases are pulled.
+	Channel string
+	// TagPattern is a regular expression the release tags must match, with a "version" named
+	// group capturing the version, e.g. "git-clone/(?P<version>.*)" for monorepos. When empty,
+	// the version is the tag without its "v" prefix.
+	TagPattern string `json:"tag-pattern"`
+	// Trust pins the key the resources of the repository are signed with, their signatures
+	// are not verified when nil.
+	Trust *Trust `json:"trust,omitempty"`
+	// Mirror is the local directory the releases are read from instead of the URL, set from
+	// the mirror-root of the configuration.
+	Mirror string `json:"-"`
+}
+
+// Trust is the public key the resources of a repository must be signed with, and what to do
+// with the resources that aren't.
+type Trust struct {
+	// PublicKey is the cosign public key: a file, relative to the configuration file, or any
+	// key reference cosign supports (e.g. "k8s://namespace/secret").
+	PublicKey string `json:"public-key"`
+	// Policy tells what to do with the unsigned or invalidly signed resources (enforce,
+	// allow-unsigned, warn), enforce when empty.
+	Policy string
+}
+
+// GetPolicy returns the trust policy, enforce when not set.
+func (t Trust) GetPolicy() string {
+	if t.Policy == "" {
+		return PolicyEnforce
+	}
+	return t.Policy
+}
+
+// Validate makes sure the trust has a public key and a supported policy.
+func (t Trust) Validate() error {
+	if t.PublicKey == "" {
+		return fmt.Errorf("trust has no public-key")
+	}
+	switch t.Policy {
+	case "", PolicyEnforce, PolicyAllowUnsigned, PolicyWarn:
+		return nil
+	default:
+		return fmt.Errorf("unsupported trust policy %q", t.Policy)
+	}
+}
+
+// TagVersion extracts the version from the release tag, without its "v" prefix. It reports
+// false when the tag doesn't match the repository tag-pattern.
+func (r Repository) TagVersion(tag string) (string, bool) {
+	if r.TagPattern == "" {
+		return strings.TrimPrefix(tag, "v"), true
+	}
+	re, err := tagPattern(r.TagPattern)
+	if err != nil {
+		return "", false
+	}
+	m := re.FindStringSubmatch(tag)
+	if m == nil {
+		return "", false
+	}
+	return strings.TrimPrefix(m[re.SubexpIndex("version")], "v"), true
+}
+
+// tagPattern compiles the pattern to match whole tags, it must have a "version" group.
+func tagPattern(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid tag-pattern %q: %w", pattern, err)
+	}
+	if re.SubexpIndex("version") < 0 {
+		return nil, fmt.Errorf("invalid tag-pattern %q: no \"version\" named group", pattern)
+	}
+	return re, nil
+}
+
+// GetProvider returns the provider of the repository, guessing it from the URL when it is
+// not explicitly set. A mirrored repository is always a local one.
+func (r Repository) GetProvider() string {
+	if r.Mirror != "" {
+		return ProviderLocal
+	}
+	if r.Provider != "" {
+		return r.Provider
+	}
+	if strings.HasPrefix(r.URL, "oci://") {
+		return ProviderOCI
+	}
+	if strings.HasPrefix(r.URL, "file://") {
+		return ProviderLocal
+	}
+	if u, err := url.Parse(r.URL); err == nil && strings.Contains(u.Host, "gitlab") {
+		return ProviderGitLab
+	}
+	return ProviderGitHub
 }
 
 // setDefaults sets the default values for the configuration.
@@ -35,11 +183,114 @@ func setDefaults(e External) External {
 		if r.ResourcesTarballName == "" {
 			r.ResourcesTarballName = contract.ResourcesName
 		}
+		if r.Channel == "" {
+			r.Channel = e.Channel
+		}
+		e.Repositories[i] = r
+	}
+	return e
+}
+
+// Mirrored reads the remote repositories from the mirror root, nothing changing when root is
+// empty. The local repositories are left as is.
+func (e External) Mirrored(root string) External {
+	e.MirrorRoot = root
+	for i, r := range e.Repositories {
+		u, err := url.Parse(r.URL)
+		if root == "" || err != nil || u.Host == "" || u.Scheme == "file" {
+			continue
+		}
+		r.Mirror = MirrorPath(root, r.URL)
 		e.Repositories[i] = r
 	}
 	return e
 }
 
+// MirrorPath returns the folder mirroring the repository URL under the mirror root, the
+// <host>/<path> of the URL.
+func MirrorPath(root, repositoryURL string) string {
+	u, err := url.Parse(repositoryURL)
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(root, u.Host, filepath.FromSlash(strings.TrimSuffix(u.Path, ".git")))
+}
+
+// resolvePublicKeys makes the public key files of the trusts relative to dir, the folder of
+// the configuration file. Other key references are left as is.
+func resolvePublicKeys(e External, dir string) {
+	for _, r := range e.Repositories {
+		if r.Trust == nil || strings.Contains(r.Trust.PublicKey, "://") || filepath.IsAbs(r.Trust.PublicKey) {
+			continue
+		}
+		r.Trust.PublicKey = filepath.Join(dir, r.Trust.PublicKey)
+	}
+}
+
+// ValidateURL makes sure the repository URL has a scheme, only the paths of the repositories
+// with the local provider, or the git one for a repository on disk, having none.
+func (r Repository) ValidateURL() error {
+	if strings.Contains(r.URL, "://") || r.Provider == ProviderLocal || r.Provider == ProviderGit {
+		return nil
+	}
+	return fmt.Errorf("invalid URL %q, a scheme (https://, file://, oci://) is expected unless the provider is local or git", r.URL)
+}
+
+// ValidateChannel makes sure the channel is a supported one, empty meaning stable.
+func ValidateChannel(channel string) error {
+	switch channel {
+	case "", ChannelStable, ChannelPreview, ChannelDraft:
+		return nil
+	default:
+		return fmt.Errorf("unsupported channel %q", channel)
+	}
+}
+
+// ValidateTagPattern makes sure the tag-pattern compiles and captures the version, empty
+// meaning no pattern.
+func ValidateTagPattern(pattern string) error {
+	if pattern == "" {
+		return nil
+	}
+	_, err := tagPattern(pattern)
+	return err
+}
+
+// validate makes sure the configuration only refers to supported values.
+func validate(e External) error {
+	if err := ValidateChannel(e.Channel); err != nil {
+		return err
+	}
+	for _, r := range e.Repositories {
+		switch p := r.GetProvider(); p {
+		case ProviderGitHub, ProviderGitLab, ProviderLocal, ProviderGit, ProviderOCI:
+		default:
+			return fmt.Errorf("unsupported provider %q for %s", p, r.URL)
+		}
+		if err := r.ValidateURL(); err != nil {
+			return err
+		}
+		if r.MaxReleases < 0 {
+			return fmt.Errorf("invalid max-releases %d for %s", r.MaxReleases, r.URL)
+		}
+		if err := r.Versions.Validate(); err != nil {
+			return fmt.Errorf("%w for %s", err, r.URL)
+		}
+		if err := ValidateChannel(r.Channel); err != nil {
+			return fmt.Errorf("%w for %s", err, r.URL)
+		}
+		if err := ValidateTagPattern(r.TagPattern); err != nil {
+			return fmt.Errorf("%w for %s", err, r.URL)
+		}
+		if r.Trust != nil {
+			if err := r.Trust.Validate(); err != nil {
+				return fmt.Errorf("%w for %s", err, r.URL)
+			}
+		}
+	}
+	return nil
+}
+
 func LoadExternal(filename string) (External, error) {
 	var c External
 	data, err := os.ReadFile(filename)
@@ -49,6 +300,13 @@ func LoadExternal(filename string) (External, error) {
 	if err := yaml.Unmarshal(data, &c); err != nil {
 		return External{}, fmt.Errorf("could not load external configuration from %s: %w", filename, err)
 	}
+	if err := validate(c); err != nil {
+		return External{}, fmt.Errorf("could not load external configuration from %s: %w", filename, err)
+	}
 	c = setDefaults(c)
-	return c, nil
+	resolvePublicKeys(c, filepath.Dir(filename))
+	if c.MirrorRoot != "" && !filepath.IsAbs(c.MirrorRoot) {
+		c.MirrorRoot = filepath.Join(filepath.Dir(filename), c.MirrorRoot)
+	}
+	return c.Mirrored(c.MirrorRoot), nil
 }
@@ -51,3 +51,87 @@ func TestLoadExternalNonExisting(t *testing.T) {
 		t.Fatalf("Should have errored out on non existing file : %v", err)
 	}
 }
+
+func TestTagVersion(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, tag, version string
+		ok                    bool
+	}{
+		{tag: "v1.2.0", version: "1.2.0", ok: true},
+		{tag: "1.2.0", version: "1.2.0", ok: true},
+		{pattern: "git-clone/(?P<version>.*)", tag: "git-clone/v1.2.0", version: "1.2.0", ok: true},
+		{pattern: "git-clone/(?P<version>.*)", tag: "git-init/v1.2.0"},
+		{pattern: "git-clone/(?P<version>.*)", tag: "v1.2.0"},
+		{pattern: "(?P<version>[0-9.]+)-stable", tag: "1.2.0-stable", version: "1.2.0", ok: true},
+	} {
+		r := config.Repository{TagPattern: tc.pattern}
+		version, ok := r.TagVersion(tc.tag)
+		if version != tc.version || ok != tc.ok {
+			t.Errorf("%q with pattern %q: expected (%q, %v), got (%q, %v)", tc.tag, tc.pattern, tc.version, tc.ok, version, ok)
+		}
+	}
+}
+
+func TestLoadExternalTrust(t *testing.T) {
+	e, err := config.LoadExternal(filepath.Join("testdata", "external.trust.valid.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range []config.Trust{
+		{PublicKey: filepath.Join("testdata", "keys", "golang-tasks.pub")},
+		{PublicKey: "k8s://tekton-pipelines/signing-secrets", Policy: config.PolicyAllowUnsigned},
+	} {
+		if trust := e.Repositories[i].Trust; trust == nil || *trust != expected {
+			t.Errorf("%s: expected trust %+v, got %+v", e.Repositories[i].Name, expected, trust)
+		}
+	}
+	if policy := e.Repositories[0].Trust.GetPolicy(); policy != config.PolicyEnforce {
+		t.Errorf("expected the %s policy by default, got %s", config.PolicyEnforce, policy)
+	}
+}
+
+func TestLoadExternalMirrorRoot(t *testing.T) {
+	e, err := config.LoadExternal(filepath.Join("testdata", "external.mirror-root.valid.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.Join("testdata", "mirror")
+	for i, expected := range []string{
+		filepath.Join(root, "github.com", "shortbrain", "golang-tasks"),
+		filepath.Join(root, "gitlab.com", "group", "subgroup", "tasks"),
+		"",
+	} {
+		r := e.Repositories[i]
+		if r.Mirror != expected {
+			t.Errorf("%s: expected mirror %q, got %q", r.URL, expected, r.Mirror)
+		}
+		if provider := r.GetProvider(); provider != config.ProviderLocal {
+			t.Errorf("%s: expected the %s provider, got %s", r.URL, config.ProviderLocal, provider)
+		}
+	}
+}
+
+func TestGetProvider(t *testing.T) {
+	for _, tc := range []struct {
+		url, provider, expected string
+	}{
+		{url: "https://github.com/org/repo", expected: config.ProviderGitHub},
+		{url: "https://gitlab.example.com/group/repo", expected: config.ProviderGitLab},
+		{url: "oci://ghcr.io/org/repo", expected: config.ProviderOCI},
+		{url: "file:///srv/mirror/repo", expected: config.ProviderLocal},
+		{url: "./mirror/repo", provider: config.ProviderLocal, expected: config.ProviderLocal},
+		{url: "https://git.example.com/org/repo", provider: config.ProviderGit, expected: config.ProviderGit},
+		{url: "/srv/git/repo.git", provider: config.ProviderGit, expected: config.ProviderGit},
+	} {
+		r := config.Repository{URL: tc.url, Provider: tc.provider}
+		if provider := r.GetProvider(); provider != tc.expected {
+			t.Errorf("%s: expected the %s provider, got %s", tc.url, tc.expected, provider)
+		}
+		if err := r.ValidateURL(); err != nil {
+			t.Errorf("%s: %v", tc.url, err)
+		}
+	}
+	if err := (config.Repository{URL: "github.com/org/repo"}).ValidateURL(); err == nil {
+		t.Errorf("a URL without scheme should be rejected unless the provider is local or git")
+	}
+}
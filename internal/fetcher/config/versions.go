@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/blang/semver"
+)
+
+// Versions is a policy selecting which released versions of a repository are pulled. Versions
+// are read as semantic versions, with or without the "v" prefix; when a policy is set, tags
+// that are not semantic versions are left out.
+type Versions struct {
+	// Constraint is a range the versions must satisfy, e.g. ">=0.3.0 <2.0.0".
+	Constraint string `json:"constraint,omitempty"`
+	// LatestMinors keeps the versions of the N latest minor versions only.
+	LatestMinors int `json:"latest-minors,omitempty"`
+	// LatestPatch keeps the latest patch version of each minor version only.
+	LatestPatch bool `json:"latest-patch,omitempty"`
+}
+
+// IsSet returns true when the policy filters anything out.
+func (p Versions) IsSet() bool {
+	return p.Constraint != "" || p.LatestMinors > 0 || p.LatestPatch
+}
+
+// Validate checks the constraint parses and the numbers make sense.
+func (p Versions) Validate() error {
+	if p.Constraint != "" {
+		if _, err := semver.ParseRange(p.Constraint); err != nil {
+			return fmt.Errorf("invalid versions constraint %q: %w", p.Constraint, err)
+		}
+	}
+	if p.LatestMinors < 0 {
+		return fmt.Errorf("invalid versions latest-minors %d", p.LatestMinors)
+	}
+	return nil
+}
+
+// Select returns the tags matching the policy, in the order they are given.
+func (p Versions) Select(tags []string) ([]string, error) {
+	if !p.IsSet() {
+		return tags, nil
+	}
+	inRange := func(semver.Version) bool { return true }
+	if p.Constraint != "" {
+		r, err := semver.ParseRange(p.Constraint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid versions constraint %q: %w", p.Constraint, err)
+		}
+		inRange = r
+	}
+
+	type minor struct{ major, minor uint64 }
+	versions := map[string]semver.Version{}
+	latest := map[minor]semver.Version{}
+	for _, tag := range tags {
+		v, err := semver.ParseTolerant(tag)
+		if err != nil || !inRange(v) {
+			continue
+		}
+		versions[tag] = v
+		m := minor{v.Major, v.Minor}
+		if l, ok := latest[m]; !ok || v.GT(l) {
+			latest[m] = v
+		}
+	}
+
+	minors := make([]minor, 0, len(latest))
+	for m := range latest {
+		minors = append(minors, m)
+	}
+	sort.Slice(minors, func(i, j int) bool {
+		return minors[i].major > minors[j].major || minors[i].major == minors[j].major && minors[i].minor > minors[j].minor
+	})
+	if p.LatestMinors > 0 && len(minors) > p.LatestMinors {
+		for _, m := range minors[p.LatestMinors:] {
+			delete(latest, m)
+		}
+	}
+
+	selected := []string{}
+	for _, tag := range tags {
+		v, ok := versions[tag]
+		if !ok {
+			continue
+		}
+		l, ok := latest[minor{v.Major, v.Minor}]
+		if !ok || p.LatestPatch && !v.EQ(l) {
+			continue
+		}
+		selected = append(selected, tag)
+	}
+	return selected, nil
+}
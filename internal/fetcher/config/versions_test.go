@@ -0,0 +1,46 @@
+package config_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
+)
+
+func TestVersionsSelect(t *testing.T) {
+	tags := []string{"v2.1.0", "v2.0.1", "v2.0.0", "v1.2.3", "v1.2.2", "v1.1.0", "0.3.1", "v0.3.0", "v0.2.0", "latest"}
+	for _, tc := range []struct {
+		name     string
+		policy   config.Versions
+		expected []string
+	}{{
+		name:     "no policy",
+		expected: tags,
+	}, {
+		name:     "constraint",
+		policy:   config.Versions{Constraint: ">=0.3.0 <2.0.0"},
+		expected: []string{"v1.2.3", "v1.2.2", "v1.1.0", "0.3.1", "v0.3.0"},
+	}, {
+		name:     "latest minors",
+		policy:   config.Versions{LatestMinors: 3},
+		expected: []string{"v2.1.0", "v2.0.1", "v2.0.0", "v1.2.3", "v1.2.2"},
+	}, {
+		name:     "latest patch",
+		policy:   config.Versions{LatestPatch: true},
+		expected: []string{"v2.1.0", "v2.0.1", "v1.2.3", "v1.1.0", "0.3.1", "v0.2.0"},
+	}, {
+		name:     "all combined",
+		policy:   config.Versions{Constraint: "<2.0.0", LatestMinors: 2, LatestPatch: true},
+		expected: []string{"v1.2.3", "v1.1.0"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			selected, err := tc.policy.Select(tags)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(selected, ",") != strings.Join(tc.expected, ",") {
+				t.Fatalf("Should have selected %v, got %v", tc.expected, selected)
+			}
+		})
+	}
+}
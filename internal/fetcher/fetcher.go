@@ -1,62 +1,129 @@
 package fetcher
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"strings"
+	"path/filepath"
+	"slices"
+	"time"
 
-	"github.com/cli/go-gh/v2/pkg/api"
 	"github.com/openshift-pipelines/catalog-cd/internal/contract"
 	"github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
 )
 
-// FetchContractsFromRepository fetches contracts from a repository.
-func FetchContractsFromRepository(r config.Repository, client *api.RESTClient) (map[string]*contract.Contract, error) {
-	m := map[string]*contract.Contract{}
+// Release is a repository release, along with the contract it publishes and the location
+// of its contract and resources tarball.
+type Release struct {
+	Version
+	Contract     *contract.Contract
+	ContractURL  string
+	ResourcesURL string
+}
 
-	if !strings.HasPrefix(r.URL, "https://github.com") {
-		return m, fmt.Errorf("non-github repository not supported: %s", r.URL)
-	}
-	repo := strings.TrimPrefix(r.URL, "https://github.com/")
-	versions, err := fetchVersions(repo, client)
+// FetchContractsFromRepository fetches contracts from a repository, using the given source.
+// Only the versions of the repository channel, matching its tag-pattern and selected by its
+// versions policy are fetched.
+func FetchContractsFromRepository(ctx context.Context, r config.Repository, s Source) (map[string]Release, error) {
+	m := map[string]Release{}
+
+	versions, err := s.ListVersions(ctx)
 	if err != nil {
 		return m, fmt.Errorf("failed to fetch versions from %s: %w", r.URL, err)
 	}
+	versions, err = selectVersions(r, versions)
+	if err != nil {
+		return m, err
+	}
 	for _, v := range versions {
-		if v.PreRelease || v.Draft {
-			// Ignore drafts or pre-releases
-			continue
-		}
-		var contractAsset Asset
-		contractFound := false
-		for _, a := range v.Assets {
-			// catalog.yml is there for backward-compatibility
-			if a.Name == r.CatalogName || a.Name == "catalog.yml" {
-				contractFound = true
-				contractAsset = a
-				break
-			}
-		}
-		if !contractFound {
+		// Load contract from asset
+		contract, err := s.FetchContract(ctx, v)
+		if errors.Is(err, ErrContractNotFound) {
 			// FIXME(vdemeester) should we ignore or error out ?
 			continue
 		}
-		// Load contract from asset
-		contract, err := contract.NewContractFromURL(contractAsset.DownloadURL)
 		if err != nil {
-			return m, fmt.Errorf("failed to load asset %s from %s: %w", contractAsset.Name, v.TagName, err)
+			return m, fmt.Errorf("failed to load contract from %s: %w", v.TagName, err)
+		}
+		m[v.TagName] = Release{
+			Version:      v,
+			Contract:     contract,
+			ContractURL:  contractURL(r, v),
+			ResourcesURL: resourcesURL(r, v),
 		}
-		m[v.TagName] = contract
 	}
 	return m, nil
 }
 
-func fetchVersions(github string, client *api.RESTClient) ([]Version, error) {
-	versions := []Version{}
-	err := client.Get(fmt.Sprintf("repos/%s/releases", github), &versions)
+// selectVersions drops the drafts and pre-releases not part of the repository channel, the
+// ignored tags and the ones not matching its tag-pattern, then the versions left out by its
+// versions policy.
+func selectVersions(r config.Repository, versions []Version) ([]Version, error) {
+	released := map[string]Version{}
+	names := []string{}
+	for _, v := range versions {
+		if v.Draft && r.Channel != config.ChannelDraft {
+			continue
+		}
+		if v.PreRelease && r.Channel != config.ChannelPreview && r.Channel != config.ChannelDraft {
+			continue
+		}
+		if slices.Contains(r.IgnoreVersions, v.TagName) {
+			continue
+		}
+		name, ok := r.TagVersion(v.TagName)
+		if !ok {
+			continue
+		}
+		if _, exists := released[name]; exists {
+			continue
+		}
+		released[name] = v
+		names = append(names, name)
+	}
+	names, err := r.Versions.Select(names)
 	if err != nil {
 		return nil, err
 	}
-	return versions, nil
+	selected := make([]Version, 0, len(names))
+	for _, name := range names {
+		selected = append(selected, released[name])
+	}
+	return selected, nil
+}
+
+// contractURL returns the location of the contract of the given version.
+func contractURL(r config.Repository, v Version) string {
+	switch r.GetProvider() {
+	case config.ProviderGit:
+		// the contract is read from the git tree
+		return r.URL
+	case config.ProviderOCI:
+		return fmt.Sprintf("%s:%s", r.URL, v.TagName)
+	}
+	if a, ok := findAsset(v.Assets, r.CatalogName, "catalog.yml"); ok {
+		return a.DownloadURL
+	}
+	return r.URL
+}
+
+// resourcesURL returns the location of the resources tarball of the given version, or of the
+// repository when the version doesn't publish any.
+func resourcesURL(r config.Repository, v Version) string {
+	switch r.GetProvider() {
+	case config.ProviderGitHub, config.ProviderGitLab:
+		if a, ok := findAsset(v.Assets, r.ResourcesTarballName); ok {
+			return a.DownloadURL
+		}
+	case config.ProviderLocal:
+		return filepath.Join(LocalPath(r), v.TagName, r.ResourcesTarballName)
+	case config.ProviderGit:
+		// resources are read from the git tree
+		return r.URL
+	case config.ProviderOCI:
+		return fmt.Sprintf("%s:%s", r.URL, v.TagName)
+	}
+	return r.URL
 }
 
 type Version struct {
@@ -68,6 +135,8 @@ type Version struct {
 	Assets     []Asset
 	URL        string `json:"url"`
 	TarballURL string `json:"tarball_url"`
+	// PublishedAt is when the version has been released, zero when unknown.
+	PublishedAt time.Time `json:"published_at"`
 }
 
 type Asset struct {
@@ -78,4 +147,6 @@ type Asset struct {
 	ContentType string `json:"content_type"`
 	State       string
 	DownloadURL string `json:"browser_download_url"`
+	// UpdatedAt is when the asset has last been updated, zero when unknown.
+	UpdatedAt time.Time `json:"updated_at"`
 }
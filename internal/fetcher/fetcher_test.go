@@ -1,23 +1,44 @@
 package fetcher_test
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
 	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/openshift-pipelines/catalog-cd/internal/cache"
+	"github.com/openshift-pipelines/catalog-cd/internal/contract"
 	"github.com/openshift-pipelines/catalog-cd/internal/fetcher"
 	"github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
+	"github.com/openshift-pipelines/catalog-cd/internal/oci"
 	"gopkg.in/h2non/gock.v1"
 )
 
 func TestFetchContractFromRepository(t *testing.T) {
-	t.Skip("Skipping, need to be rewritten")
 	t.Cleanup(gock.Off)
 
 	repo := config.Repository{
-		Name: "golang-task",
-		URL:  "https://github.com/shortbrain/golang-tasks",
+		Name:        "golang-task",
+		URL:         "https://github.com/shortbrain/golang-tasks",
+		CatalogName: "contract.yaml",
 	}
 	r := strings.TrimPrefix(repo.URL, "https://github.com/")
 
@@ -25,20 +46,435 @@ func TestFetchContractFromRepository(t *testing.T) {
 		Get(fmt.Sprintf("repos/%s/releases", r)).
 		Reply(200).
 		File("testdata/releases.yaml")
-	gock.New("https://github.com").
-		Get(fmt.Sprintf("%s/releases/download/v1.0.0/contract.yaml", r)).
+	gock.New("https://api.github.com").
+		Get(fmt.Sprintf("repos/%s/releases/assets/1", r)).
+		MatchHeader("Accept", "application/octet-stream").
+		Reply(200).
+		File("../catalog/testdata/catalog.simple.yaml")
+
+	client, err := api.NewRESTClient(api.ClientOptions{Host: "github.com", AuthToken: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := fetcher.NewGitHubSource(repo, client, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := fetcher.FetchContractsFromRepository(context.Background(), repo, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 1 {
+		t.Fatalf("Should have fetched only 1 version, fetched %d: %v", len(m), m)
+	}
+}
+
+func TestFetchContractFromRepositoryCached(t *testing.T) {
+	t.Cleanup(gock.Off)
+
+	repo := config.Repository{
+		Name:        "golang-task",
+		URL:         "https://github.com/shortbrain/golang-tasks",
+		CatalogName: "contract.yaml",
+	}
+	r := strings.TrimPrefix(repo.URL, "https://github.com/")
+	// The contract asset is downloaded once, then served from the cache.
+	gock.New("https://api.github.com").
+		Get(fmt.Sprintf("repos/%s/releases/assets/1", r)).
+		MatchHeader("Accept", "application/octet-stream").
+		Times(1).
+		Reply(200).
+		File("../catalog/testdata/catalog.simple.yaml")
+	gock.New("https://api.github.com").
+		Get(fmt.Sprintf("repos/%s/releases$", r)).
+		Times(2).
+		Reply(200).
+		File("testdata/releases.yaml")
+
+	client, err := api.NewRESTClient(api.ClientOptions{Host: "github.com", AuthToken: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources := fetcher.DefaultSources(fetcher.Clients{
+		GitHub: func(string) (*api.RESTClient, *http.Client, error) { return client, nil, nil },
+		Cache:  cache.New(t.TempDir()),
+	})
+	for i := 0; i < 2; i++ {
+		s, err := sources(repo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := fetcher.FetchContractsFromRepository(context.Background(), repo, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(m) != 1 {
+			t.Fatalf("Should have fetched only 1 version, fetched %d: %v", len(m), m)
+		}
+	}
+	if !gock.IsDone() {
+		t.Fatalf("Should have listed the releases twice and downloaded the contract once")
+	}
+}
+
+func TestListGitHubVersionsPaginated(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		maxReleases int
+		expected    []string
+	}{
+		{name: "all", expected: []string{"v1.3.0", "v1.2.0", "v1.1.0", "v1.0.0"}},
+		{name: "capped", maxReleases: 3, expected: []string{"v1.3.0", "v1.2.0", "v1.1.0"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Cleanup(gock.Off)
+
+			gock.New("https://api.github.com").
+				Get("repos/shortbrain/golang-tasks/releases").
+				MatchParam("per_page", "100").
+				Reply(200).
+				SetHeader("Link", `<https://api.github.com/repositories/1/releases?per_page=100&page=2>; rel="next", <https://api.github.com/repositories/1/releases?per_page=100&page=2>; rel="last"`).
+				JSON(`[{"tag_name": "v1.3.0"}, {"tag_name": "v1.2.0"}]`)
+			gock.New("https://api.github.com").
+				Get("repositories/1/releases").
+				MatchParam("page", "2").
+				Reply(200).
+				SetHeader("Link", `<https://api.github.com/repositories/1/releases?per_page=100&page=1>; rel="prev"`).
+				JSON(`[{"tag_name": "v1.1.0"}, {"tag_name": "v1.0.0"}]`)
+
+			client, err := api.NewRESTClient(api.ClientOptions{Host: "github.com", AuthToken: "token"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			s, err := fetcher.NewGitHubSource(config.Repository{
+				URL:         "https://github.com/shortbrain/golang-tasks",
+				MaxReleases: tc.maxReleases,
+			}, client, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			versions, err := s.ListVersions(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			tags := []string{}
+			for _, v := range versions {
+				tags = append(tags, v.TagName)
+			}
+			if strings.Join(tags, ",") != strings.Join(tc.expected, ",") {
+				t.Fatalf("Should have listed %v, got %v", tc.expected, tags)
+			}
+		})
+	}
+}
+
+func TestListGitHubEnterpriseVersions(t *testing.T) {
+	t.Cleanup(gock.Off)
+
+	gock.New("https://ghe.example.com").
+		Get("api/v3/repos/tekton/tasks/releases").
+		MatchHeader("Authorization", "token ghe-token").
+		Reply(200).
+		JSON(`[{"tag_name": "v0.2.0"}, {"tag_name": "v0.1.0"}]`)
+
+	client, err := api.NewRESTClient(api.ClientOptions{Host: "ghe.example.com", AuthToken: "ghe-token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hosts := []string{}
+	sources := fetcher.DefaultSources(fetcher.Clients{
+		GitHub: func(host string) (*api.RESTClient, *http.Client, error) {
+			hosts = append(hosts, host)
+			return client, nil, nil
+		},
+	})
+	s, err := sources(config.Repository{
+		URL:      "https://ghe.example.com/tekton/tasks",
+		Provider: config.ProviderGitHub,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions, err := s.ListVersions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(hosts, ",") != "ghe.example.com" {
+		t.Fatalf("Should have asked for the ghe.example.com clients, got %v", hosts)
+	}
+	if len(versions) != 2 || versions[0].TagName != "v0.2.0" {
+		t.Fatalf("Should have listed v0.2.0 and v0.1.0, got %v", versions)
+	}
+}
+
+func TestFetchContractFromGitLabRepository(t *testing.T) {
+	t.Cleanup(gock.Off)
+
+	repo := config.Repository{
+		Name:                 "golang-task",
+		URL:                  "https://gitlab.com/shortbrain/golang-tasks",
+		CatalogName:          "catalog.yaml",
+		ResourcesTarballName: "resources.tar.gz",
+	}
+
+	gock.New("https://gitlab.com").
+		Get("api/v4/projects/shortbrain/golang-tasks/releases").
+		Reply(200).
+		File("testdata/gitlab.releases.json")
+	gock.New("https://gitlab.com").
+		Get("shortbrain/golang-tasks/-/releases/v1.0.0/downloads/catalog.yaml").
+		Reply(200).
+		File("../catalog/testdata/catalog.simple.yaml")
+
+	s, err := fetcher.NewGitLabSource(repo, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := fetcher.FetchContractsFromRepository(context.Background(), repo, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 1 {
+		t.Fatalf("Should have fetched only 1 version, fetched %d: %v", len(m), m)
+	}
+	release, ok := m["v1.0.0"]
+	if !ok {
+		t.Fatalf("Should have fetched version v1.0.0, got %v", m)
+	}
+	expected := "https://gitlab.com/shortbrain/golang-tasks/-/releases/v1.0.0/downloads/resources.tar.gz"
+	if release.ResourcesURL != expected {
+		t.Fatalf("Should have used the resources asset link %s, got %s", expected, release.ResourcesURL)
+	}
+	if len(release.Contract.Catalog.Resources.Tasks) != 2 {
+		t.Fatalf("Should have loaded 2 tasks from the contract, got %d", len(release.Contract.Catalog.Resources.Tasks))
+	}
+}
+
+func TestFetchContractFromGitLabRepositoryAuthenticated(t *testing.T) {
+	t.Cleanup(gock.Off)
+	t.Setenv("GITLAB_TOKEN", "token")
+
+	repo := config.Repository{
+		Name:                 "golang-task",
+		URL:                  "https://gitlab.com/shortbrain/golang-tasks",
+		CatalogName:          "catalog.yaml",
+		ResourcesTarballName: "resources.tar.gz",
+	}
+
+	gock.New("https://gitlab.com").
+		Get("api/v4/projects/shortbrain/golang-tasks/releases").
+		MatchHeader("PRIVATE-TOKEN", "token").
 		Reply(200).
-		File("config/testdata/contract.simple.yaml")
+		File("testdata/gitlab.releases.json")
+	gock.New("https://gitlab.com").
+		Get("shortbrain/golang-tasks/-/releases/v1.0.0/downloads/catalog.yaml").
+		MatchHeader("PRIVATE-TOKEN", "token").
+		Reply(200).
+		File("../catalog/testdata/catalog.simple.yaml")
+
+	s, err := fetcher.NewGitLabSource(repo, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := fetcher.FetchContractsFromRepository(context.Background(), repo, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 1 {
+		t.Fatalf("Should have fetched only 1 version, fetched %d: %v", len(m), m)
+	}
+}
+
+func TestFetchContractFromLocalRepositoryPreReleases(t *testing.T) {
+	root := t.TempDir()
+	for _, tag := range []string{"v0.1.0", "v0.2.0-rc.1"} {
+		copyFile(t, "../catalog/testdata/catalog.simple.yaml", filepath.Join(root, tag, "catalog.yaml"))
+	}
+
+	for _, tc := range []struct {
+		channel  string
+		expected []string
+	}{
+		{channel: "", expected: []string{"v0.1.0"}},
+		{channel: config.ChannelPreview, expected: []string{"v0.1.0", "v0.2.0-rc.1"}},
+	} {
+		repo := config.Repository{URL: root, Provider: config.ProviderLocal, CatalogName: "catalog.yaml", Channel: tc.channel}
+		s, err := fetcher.NewLocalSource(repo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := fetcher.FetchContractsFromRepository(context.Background(), repo, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tags := slices.Sorted(maps.Keys(m))
+		if !slices.Equal(tags, tc.expected) {
+			t.Fatalf("Should have fetched %v on the %q channel, got %v", tc.expected, tc.channel, tags)
+		}
+	}
+}
+
+// git runs a git command in dir, failing the test on error.
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	args = append([]string{"-c", "user.name=catalog-cd", "-c", "user.email=catalog-cd@example.com", "-c", "commit.gpgsign=false", "-c", "tag.gpgsign=false"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+	}
+}
+
+// copyFile copies src into the dst file, creating its parent directory.
+func copyFile(t *testing.T, src, dst string) {
+	t.Helper()
+	data, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dst, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFetchContractFromGitRepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+	work := t.TempDir()
+	git(t, work, "init", "--quiet")
+	copyFile(t, "../catalog/testdata/tasks/go-ko-image/README.md", filepath.Join(work, "README.md"))
+	git(t, work, "add", ".")
+	git(t, work, "commit", "--quiet", "-m", "Initial commit")
+	git(t, work, "tag", "-a", "-m", "No contract", "v0.0.1")
+
+	task := filepath.Join(work, "tasks", "go-ko-image", "go-ko-image.yaml")
+	copyFile(t, "../catalog/testdata/tasks/go-ko-image/go-ko-image.yaml", task)
+	copyFile(t, "../catalog/testdata/tasks/go-ko-image/README.md", filepath.Join(work, "tasks", "go-ko-image", "README.md"))
+	c := contract.NewContractEmpty()
+	if err := c.AddResourceFile(task, "0.1.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SaveAs(filepath.Join(work, contract.Filename)); err != nil {
+		t.Fatal(err)
+	}
+	git(t, work, "add", ".")
+	git(t, work, "commit", "--quiet", "-m", "Add go-ko-image")
+	git(t, work, "tag", "-a", "-m", "Release v0.1.0", "v0.1.0")
+
+	remote := filepath.Join(t.TempDir(), "tasks.git")
+	git(t, work, "clone", "--quiet", "--bare", work, remote)
+
+	repo := config.Repository{
+		URL:                  remote,
+		Provider:             config.ProviderGit,
+		CatalogName:          contract.Filename,
+		ResourcesTarballName: contract.ResourcesName,
+	}
+	s, err := fetcher.NewGitSource(repo, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := fetcher.FetchContractsFromRepository(context.Background(), repo, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 1 {
+		t.Fatalf("Should have fetched only 1 version, fetched %d: %v", len(m), m)
+	}
+	release, ok := m["v0.1.0"]
+	if !ok {
+		t.Fatalf("Should have fetched version v0.1.0, got %v", m)
+	}
+
+	resources, err := s.OpenResources(context.Background(), release.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resources.Close()
+	gzr, err := gzip.NewReader(resources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := []string{}
+	tr := tar.NewReader(gzr)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			files = append(files, header.Name)
+		}
+	}
+	expected := []string{"tasks/go-ko-image/README.md", "tasks/go-ko-image/go-ko-image.yaml"}
+	if strings.Join(files, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Should have archived %v, got %v", expected, files)
+	}
+}
+
+func TestFetchContractFromOCIRepository(t *testing.T) {
+	server := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	defer server.Close()
+	repository := strings.TrimPrefix(server.URL, "http://") + "/golang-tasks"
+
+	ctx := context.Background()
+	if err := oci.Push(ctx, repository+":v0.5.0", "../catalog/testdata/catalog.simple.yaml", "../catalog/testdata/resources.tar.gz"); err != nil {
+		t.Fatal(err)
+	}
+	// an image that is not a catalog release is ignored
+	tag, err := name.NewTag(repository + ":latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Write(tag, empty.Image); err != nil {
+		t.Fatal(err)
+	}
 
-	client, err := api.DefaultRESTClient()
+	repo := config.Repository{
+		URL:                  oci.Scheme + repository,
+		CatalogName:          contract.Filename,
+		ResourcesTarballName: contract.ResourcesName,
+	}
+	s, err := fetcher.DefaultSources(fetcher.Clients{})(repo)
 	if err != nil {
 		t.Fatal(err)
 	}
-	m, err := fetcher.FetchContractsFromRepository(repo, client)
+	m, err := fetcher.FetchContractsFromRepository(ctx, repo, s)
 	if err != nil {
 		t.Fatal(err)
 	}
 	if len(m) != 1 {
 		t.Fatalf("Should have fetched only 1 version, fetched %d: %v", len(m), m)
 	}
+	release, ok := m["v0.5.0"]
+	if !ok {
+		t.Fatalf("Should have fetched version v0.5.0, got %v", m)
+	}
+	if len(release.Contract.Catalog.Resources.Tasks) != 2 {
+		t.Fatalf("Should have loaded 2 tasks from the contract, got %d", len(release.Contract.Catalog.Resources.Tasks))
+	}
+
+	resources, err := s.OpenResources(ctx, release.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resources.Close()
+	data, err := io.ReadAll(resources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := os.ReadFile("../catalog/testdata/resources.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, expected) {
+		t.Fatal("Should have fetched the pushed resources tarball")
+	}
 }
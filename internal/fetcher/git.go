@@ -0,0 +1,160 @@
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
+)
+
+// GitSource fetches releases from the tags of a plain git repository, the contract and the
+// resources are read from the git tree of each tag, the contract filenames being relative to
+// the repository root. The remote is mirrored in a local directory, using the git command.
+type GitSource struct {
+	repository config.Repository
+	dir        string // local mirror of the remote
+	settings   []string
+}
+
+var _ Source = &GitSource{}
+
+// NewGitSource instantiates a GitSource for the repository, the remote is mirrored under the
+// cache directory. The settings ("key=value") are passed to every git command, for instance
+// to configure the proxy or the CA bundle.
+func NewGitSource(r config.Repository, cacheDir string, settings ...string) (*GitSource, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("git is required to fetch %s: %w", r.URL, err)
+	}
+	sum := sha256.Sum256([]byte(r.URL))
+	return &GitSource{
+		repository: r,
+		dir:        filepath.Join(cacheDir, hex.EncodeToString(sum[:8])),
+		settings:   settings,
+	}, nil
+}
+
+// ListVersions mirrors (or updates) the remote and lists its tags.
+func (s *GitSource) ListVersions(ctx context.Context) ([]Version, error) {
+	if err := s.sync(ctx); err != nil {
+		return nil, err
+	}
+	out, err := s.git(ctx, "for-each-ref", "--format=%(refname:short)", "refs/tags")
+	if err != nil {
+		return nil, err
+	}
+	versions := []Version{}
+	for _, tag := range strings.Fields(string(out)) {
+		versions = append(versions, Version{Name: tag, TagName: tag, PreRelease: isPreRelease(s.repository, tag), URL: s.repository.URL})
+	}
+	return versions, nil
+}
+
+// FetchContract reads the contract from the tag git tree.
+func (s *GitSource) FetchContract(ctx context.Context, v Version) (*contract.Contract, error) {
+	for _, name := range []string{s.repository.CatalogName, "catalog.yml"} {
+		object := fmt.Sprintf("%s:%s", v.TagName, name)
+		if _, err := s.git(ctx, "cat-file", "-e", object); err != nil {
+			continue
+		}
+		data, err := s.git(ctx, "show", object)
+		if err != nil {
+			return nil, fmt.Errorf("could not load contract from %s: %w", object, err)
+		}
+		return contract.NewContractFromData(data)
+	}
+	return nil, fmt.Errorf("%w: no %s in %s", ErrContractNotFound, s.repository.CatalogName, v.TagName)
+}
+
+// OpenResources archives, from the tag git tree, the folders holding the resources listed in
+// the contract.
+func (s *GitSource) OpenResources(ctx context.Context, v Version) (io.ReadCloser, error) {
+	c, err := s.FetchContract(ctx, v)
+	if err != nil {
+		return nil, err
+	}
+	folders := map[string]bool{}
+	if c.Catalog.Resources != nil {
+		resources := append(append(c.Catalog.Resources.Tasks, c.Catalog.Resources.Pipelines...), c.Catalog.Resources.StepActions...)
+		for _, r := range resources {
+			folders[filepath.Dir(r.Filename)] = true
+		}
+	}
+	if len(folders) == 0 {
+		return nil, fmt.Errorf("no resources in %s contract", v.TagName)
+	}
+	args := []string{"archive", "--format=tar.gz", v.TagName, "--"}
+	for f := range folders {
+		args = append(args, f)
+	}
+	sort.Strings(args[4:])
+	out, err := s.git(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(out)), nil
+}
+
+// mirrors holds a lock per mirror directory, so that repositories sharing the same remote
+// don't sync it concurrently.
+var mirrors sync.Map
+
+// sync mirrors the remote in the local directory, or updates the existing mirror.
+func (s *GitSource) sync(ctx context.Context) error {
+	lock, _ := mirrors.LoadOrStore(s.dir, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+	if _, err := os.Stat(s.dir); err == nil {
+		_, err := s.git(ctx, "remote", "update", "--prune")
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.dir), os.ModePerm); err != nil {
+		return err
+	}
+	return runCmd(exec.CommandContext(ctx, "git", s.args("clone", "--quiet", "--mirror", s.repository.URL, s.dir)...))
+}
+
+// git runs the git command against the local mirror, returning its output.
+func (s *GitSource) git(ctx context.Context, args ...string) ([]byte, error) {
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", s.args(append([]string{"--git-dir", s.dir}, args...)...)...)
+	cmd.Stdout = &stdout
+	if err := runCmd(cmd); err != nil {
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+// args prefixes the git arguments with the source settings.
+func (s *GitSource) args(args ...string) []string {
+	prefixed := make([]string, 0, 2*len(s.settings)+len(args))
+	for _, setting := range s.settings {
+		prefixed = append(prefixed, "-c", setting)
+	}
+	return append(prefixed, args...)
+}
+
+// runCmd runs the command, reporting its standard error on failure.
+func runCmd(cmd *exec.Cmd) error {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return fmt.Errorf("%s: %w: %s", strings.Join(cmd.Args, " "), err, strings.TrimSpace(stderr.String()))
+		}
+		return err
+	}
+	return nil
+}
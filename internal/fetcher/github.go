@@ -0,0 +1,131 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/openshift-pipelines/catalog-cd/internal/cache"
+	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
+)
+
+// GitHubSource fetches releases from a GitHub repository, on github.com or on a GitHub
+// Enterprise host.
+type GitHubSource struct {
+	repository config.Repository
+	repo       string // "owner/name" of the repository
+	client     *api.RESTClient
+	assets     *http.Client
+	contracts  *cache.Cache // cache of the contract assets, nil not to cache them
+}
+
+var _ Source = &GitHubSource{}
+
+// NewGitHubSource instantiates a GitHubSource for the repository, using the client to reach
+// the API of the repository host and assets, carrying the same credentials, to download the
+// release assets.
+func NewGitHubSource(r config.Repository, client *api.RESTClient, assets *http.Client) (*GitHubSource, error) {
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return nil, err
+	}
+	repo := strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+	if u.Host == "" || strings.Count(repo, "/") != 1 {
+		return nil, fmt.Errorf("not a github repository (https://host/owner/name): %s", r.URL)
+	}
+	return &GitHubSource{
+		repository: r,
+		repo:       repo,
+		client:     client,
+		assets:     assets,
+	}, nil
+}
+
+// ListVersions lists the repository releases, following the pagination links until the
+// last page or the repository max-releases is reached.
+func (s *GitHubSource) ListVersions(ctx context.Context) ([]Version, error) {
+	versions := []Version{}
+	next := fmt.Sprintf("repos/%s/releases?per_page=100", s.repo)
+	for next != "" {
+		resp, err := s.client.RequestWithContext(ctx, http.MethodGet, next, nil)
+		if err != nil {
+			return nil, err
+		}
+		page := []Version{}
+		err = func() error {
+			defer resp.Body.Close()
+			return json.NewDecoder(resp.Body).Decode(&page)
+		}()
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, page...)
+		next = nextPage(resp.Header.Get("Link"))
+		if truncated, ok := capVersions(s.repository, versions, next != ""); ok {
+			return truncated, nil
+		}
+	}
+	return versions, nil
+}
+
+// nextPage returns the URL of the "next" relation of a Link header, or an empty string on
+// the last page.
+func nextPage(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		segments := strings.Split(part, ";")
+		if len(segments) < 2 {
+			continue
+		}
+		for _, param := range segments[1:] {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(segments[0]), "<>")
+			}
+		}
+	}
+	return ""
+}
+
+// FetchContract loads the contract from the release assets.
+func (s *GitHubSource) FetchContract(ctx context.Context, v Version) (*contract.Contract, error) {
+	return fetchContractAsset(ctx, s.openContract, s.repository, v)
+}
+
+// openContract opens the contract asset, from the cache when set. Assets are addressed by
+// their API URL, ID and update time, a re-uploaded asset getting another ID: their download
+// URLs are signed and change on every request.
+func (s *GitHubSource) openContract(ctx context.Context, a Asset) (io.ReadCloser, error) {
+	open := func() (io.ReadCloser, error) {
+		return s.openAsset(ctx, a)
+	}
+	if s.contracts == nil || a.URL == "" || a.ID == 0 {
+		return open()
+	}
+	return s.contracts.Blob(open, a.URL, strconv.Itoa(a.ID), a.UpdatedAt.UTC().Format(time.RFC3339))
+}
+
+// OpenResources downloads the resources tarball of the release.
+func (s *GitHubSource) OpenResources(ctx context.Context, v Version) (io.ReadCloser, error) {
+	return downloadResources(ctx, s.openAsset, s.repository, v)
+}
+
+// OpenAsset downloads the named asset of the release.
+func (s *GitHubSource) OpenAsset(ctx context.Context, v Version, name string) (io.ReadCloser, error) {
+	return openNamedAsset(ctx, s.openAsset, v, name)
+}
+
+// openAsset downloads the asset through the release asset API, so that assets of private
+// repositories are reachable. The API redirects to the actual content.
+func (s *GitHubSource) openAsset(ctx context.Context, a Asset) (io.ReadCloser, error) {
+	if a.URL == "" {
+		return download(ctx, s.assets, a.DownloadURL)
+	}
+	return download(ctx, s.assets, a.URL, "Accept", "application/octet-stream")
+}
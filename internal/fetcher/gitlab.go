@@ -0,0 +1,174 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
+)
+
+// GitLabSource fetches releases from a GitLab project, contract and resources are picked up
+// from the release asset links.
+type GitLabSource struct {
+	repository config.Repository
+	endpoint   string // releases API endpoint of the project
+	host       string // GitLab host, the only one the token is sent to
+	client     *http.Client
+}
+
+var _ Source = &GitLabSource{}
+
+// NewGitLabSource instantiates a GitLabSource for the repository, using the client to reach
+// the GitLab API and download the release assets (http.DefaultClient when nil). The GITLAB_TOKEN
+// environment variable is used to authenticate, when set.
+func NewGitLabSource(r config.Repository, client *http.Client) (*GitLabSource, error) {
+	endpoint, err := gitLabAPI(r.URL)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &GitLabSource{repository: r, endpoint: endpoint, host: u.Host, client: client}, nil
+}
+
+// FetchContract loads the contract from the release asset links.
+func (s *GitLabSource) FetchContract(ctx context.Context, v Version) (*contract.Contract, error) {
+	return fetchContractAsset(ctx, s.openAsset, s.repository, v)
+}
+
+// OpenResources downloads the resources tarball from the release asset links.
+func (s *GitLabSource) OpenResources(ctx context.Context, v Version) (io.ReadCloser, error) {
+	return downloadResources(ctx, s.openAsset, s.repository, v)
+}
+
+// OpenAsset downloads the named asset from the release asset links.
+func (s *GitLabSource) OpenAsset(ctx context.Context, v Version, name string) (io.ReadCloser, error) {
+	return openNamedAsset(ctx, s.openAsset, v, name)
+}
+
+// openAsset downloads the asset from its link.
+func (s *GitLabSource) openAsset(ctx context.Context, a Asset) (io.ReadCloser, error) {
+	return download(ctx, s.client, a.DownloadURL, s.authHeaders(a.DownloadURL)...)
+}
+
+// authHeaders returns the headers authenticating a request on the url: the GITLAB_TOKEN
+// environment variable when set, as long as the url is on the GitLab host since asset links
+// may point anywhere.
+func (s *GitLabSource) authHeaders(rawURL string) []string {
+	token := os.Getenv("GITLAB_TOKEN")
+	if u, err := url.Parse(rawURL); token == "" || err != nil || u.Host != s.host {
+		return nil
+	}
+	return []string{"PRIVATE-TOKEN", token}
+}
+
+// gitLabRelease represents a release as returned by the GitLab releases API.
+type gitLabRelease struct {
+	Name            string    `json:"name"`
+	TagName         string    `json:"tag_name"`
+	UpcomingRelease bool      `json:"upcoming_release"`
+	ReleasedAt      time.Time `json:"released_at"`
+	Links           struct {
+		Self string `json:"self"`
+	} `json:"_links"`
+	Assets struct {
+		Links []gitLabAssetLink `json:"links"`
+	} `json:"assets"`
+}
+
+// gitLabAssetLink represents a release asset link.
+type gitLabAssetLink struct {
+	ID             int    `json:"id"`
+	Name           string `json:"name"`
+	URL            string `json:"url"`
+	DirectAssetURL string `json:"direct_asset_url"`
+	LinkType       string `json:"link_type"`
+}
+
+// toVersion converts a GitLab release into a Version, the asset links becoming assets.
+func (r gitLabRelease) toVersion() Version {
+	v := Version{
+		Name:        r.Name,
+		TagName:     r.TagName,
+		PreRelease:  r.UpcomingRelease,
+		PublishedAt: r.ReleasedAt,
+		URL:         r.Links.Self,
+		Assets:      []Asset{},
+	}
+	for _, l := range r.Assets.Links {
+		downloadURL := l.DirectAssetURL
+		if downloadURL == "" {
+			downloadURL = l.URL
+		}
+		v.Assets = append(v.Assets, Asset{
+			ID:          l.ID,
+			URL:         l.URL,
+			Name:        l.Name,
+			DownloadURL: downloadURL,
+		})
+	}
+	return v
+}
+
+// gitLabAPI returns the releases API endpoint of the given GitLab project URL.
+func gitLabAPI(projectURL string) (string, error) {
+	u, err := url.Parse(projectURL)
+	if err != nil {
+		return "", err
+	}
+	project := strings.Trim(u.Path, "/")
+	if project == "" {
+		return "", fmt.Errorf("no project path in %s", projectURL)
+	}
+	return fmt.Sprintf("%s://%s/api/v4/projects/%s/releases", u.Scheme, u.Host, url.PathEscape(project)), nil
+}
+
+// ListVersions lists the releases of the project, following pagination until the last page
+// or the repository max-releases is reached.
+func (s *GitLabSource) ListVersions(ctx context.Context) ([]Version, error) {
+	versions := []Version{}
+	page := "1"
+	for page != "" {
+		u := fmt.Sprintf("%s?per_page=100&page=%s", s.endpoint, page)
+		req, err := newGetRequest(ctx, u, s.authHeaders(u)...)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		releases := []gitLabRelease{}
+		err = func() error {
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				return fmt.Errorf("status error: %v", resp.StatusCode)
+			}
+			return json.NewDecoder(resp.Body).Decode(&releases)
+		}()
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range releases {
+			versions = append(versions, r.toVersion())
+		}
+		page = resp.Header.Get("X-Next-Page")
+		if truncated, ok := capVersions(s.repository, versions, page != ""); ok {
+			return truncated, nil
+		}
+	}
+	return versions, nil
+}
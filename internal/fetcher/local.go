@@ -0,0 +1,120 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
+)
+
+// LocalSource fetches releases from a local directory, as found on a mirror, where each
+// version is a folder holding the contract and the resources tarball:
+//
+//	<root>/<tag>/catalog.yaml
+//	<root>/<tag>/resources.tar.gz
+type LocalSource struct {
+	repository config.Repository
+	root       string
+}
+
+var _ Source = &LocalSource{}
+
+// NewLocalSource instantiates a LocalSource for the repository, its URL being either a
+// "file://" URL or a path to the repository folder, unless it is mirrored.
+func NewLocalSource(r config.Repository) (*LocalSource, error) {
+	root := LocalPath(r)
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+	return &LocalSource{repository: r, root: root}, nil
+}
+
+// LocalPath returns the filesystem path of a local repository: its mirror, or its URL.
+func LocalPath(r config.Repository) string {
+	if r.Mirror != "" {
+		return r.Mirror
+	}
+	return filepath.Clean(strings.TrimPrefix(r.URL, "file://"))
+}
+
+// ListVersions lists the folders of the repository, each of them being a version.
+func (s *LocalSource) ListVersions(_ context.Context) ([]Version, error) {
+	entries, err := os.ReadDir(s.root)
+	if err != nil {
+		return nil, err
+	}
+	versions := []Version{}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(s.root, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		v := Version{
+			Name:       e.Name(),
+			TagName:    e.Name(),
+			PreRelease: isPreRelease(s.repository, e.Name()),
+			URL:        filepath.Join(s.root, e.Name()),
+			Assets:     []Asset{},
+		}
+		for _, f := range files {
+			if f.IsDir() {
+				continue
+			}
+			v.Assets = append(v.Assets, Asset{
+				Name:        f.Name(),
+				DownloadURL: filepath.Join(s.root, e.Name(), f.Name()),
+			})
+		}
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].TagName < versions[j].TagName
+	})
+	return versions, nil
+}
+
+// FetchContract reads the contract from the version folder.
+func (s *LocalSource) FetchContract(_ context.Context, v Version) (*contract.Contract, error) {
+	a, ok := findAsset(v.Assets, s.repository.CatalogName, "catalog.yml")
+	if !ok {
+		return nil, fmt.Errorf("%w: no %s in %s", ErrContractNotFound, s.repository.CatalogName, v.URL)
+	}
+	data, err := os.ReadFile(a.DownloadURL)
+	if err != nil {
+		return nil, fmt.Errorf("could not load contract from %s: %w", a.DownloadURL, err)
+	}
+	return contract.NewContractFromData(data)
+}
+
+// OpenResources opens the resources tarball from the version folder.
+func (s *LocalSource) OpenResources(_ context.Context, v Version) (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Join(s.root, v.TagName, s.repository.ResourcesTarballName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: no %s in %s", ErrResourcesNotFound, s.repository.ResourcesTarballName, v.URL)
+	}
+	return f, err
+}
+
+// OpenAsset opens the named file from the version folder.
+func (s *LocalSource) OpenAsset(_ context.Context, v Version, name string) (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Join(s.root, v.TagName, filepath.Base(name)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: no %s in %s", ErrAssetNotFound, name, v.URL)
+	}
+	return f, err
+}
@@ -0,0 +1,87 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
+	"github.com/openshift-pipelines/catalog-cd/internal/oci"
+)
+
+// OCISource fetches releases from an OCI repository, each tag being a version published as
+// an artifact holding the contract and the resources tarball layers.
+type OCISource struct {
+	repository config.Repository
+	ref        string // registry/repository
+	transport  http.RoundTripper
+}
+
+var _ Source = &OCISource{}
+
+// NewOCISource instantiates an OCISource for the repository, its URL being "oci://"
+// followed by the registry and repository. The registry is reached through the transport,
+// the default one when nil.
+func NewOCISource(r config.Repository, transport http.RoundTripper) (*OCISource, error) {
+	if !strings.HasPrefix(r.URL, oci.Scheme) {
+		return nil, fmt.Errorf("non-oci repository not supported: %s", r.URL)
+	}
+	return &OCISource{repository: r, ref: strings.TrimPrefix(r.URL, oci.Scheme), transport: transport}, nil
+}
+
+// ListVersions lists the repository tags.
+func (s *OCISource) ListVersions(ctx context.Context) ([]Version, error) {
+	tags, err := oci.ListTags(ctx, s.ref, oci.Transport(s.transport)...)
+	if err != nil {
+		return nil, err
+	}
+	versions := []Version{}
+	for _, tag := range tags {
+		versions = append(versions, Version{
+			Name:       tag,
+			TagName:    tag,
+			PreRelease: isPreRelease(s.repository, tag),
+			URL:        fmt.Sprintf("%s:%s", s.repository.URL, tag),
+		})
+	}
+	return versions, nil
+}
+
+// FetchContract reads the contract layer of the tagged artifact.
+func (s *OCISource) FetchContract(ctx context.Context, v Version) (*contract.Contract, error) {
+	rc, err := s.openLayer(ctx, v, oci.ContractMediaType)
+	if errors.Is(err, oci.ErrLayerNotFound) {
+		return nil, fmt.Errorf("%w: %v", ErrContractNotFound, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+	return contract.NewContractFromData(data)
+}
+
+// OpenResources opens the resources layer of the tagged artifact.
+func (s *OCISource) OpenResources(ctx context.Context, v Version) (io.ReadCloser, error) {
+	rc, err := s.openLayer(ctx, v, oci.ResourcesMediaType)
+	if errors.Is(err, oci.ErrLayerNotFound) {
+		return nil, fmt.Errorf("%w: %v", ErrResourcesNotFound, err)
+	}
+	return rc, err
+}
+
+func (s *OCISource) openLayer(ctx context.Context, v Version, mediaType types.MediaType) (io.ReadCloser, error) {
+	img, err := oci.Image(ctx, fmt.Sprintf("%s:%s", s.ref, v.TagName), oci.Transport(s.transport)...)
+	if err != nil {
+		return nil, err
+	}
+	return oci.OpenLayer(img, mediaType)
+}
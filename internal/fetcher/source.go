@@ -0,0 +1,238 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/blang/semver"
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/openshift-pipelines/catalog-cd/internal/cache"
+	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
+)
+
+var (
+	// ErrContractNotFound marks a version that doesn't publish any contract.
+	ErrContractNotFound = errors.New("contract not found")
+	// ErrResourcesNotFound marks a version that doesn't publish any resources tarball.
+	ErrResourcesNotFound = errors.New("resources not found")
+	// ErrAssetNotFound marks a version that doesn't publish the asset looked for.
+	ErrAssetNotFound = errors.New("asset not found")
+)
+
+// Source is the origin of a repository releases, it lists the released versions and gives
+// access to the contract and the resources published with each of them.
+type Source interface {
+	// ListVersions lists all the versions released in the repository.
+	ListVersions(ctx context.Context) ([]Version, error)
+	// FetchContract loads the contract published with the version, returns
+	// ErrContractNotFound when there is none.
+	FetchContract(ctx context.Context, v Version) (*contract.Contract, error)
+	// OpenResources opens the resources tarball published with the version, returns
+	// ErrResourcesNotFound when there is none.
+	OpenResources(ctx context.Context, v Version) (io.ReadCloser, error)
+}
+
+// AssetSource is implemented by the sources publishing files along with each version, as
+// the release assets of GitHub and GitLab.
+type AssetSource interface {
+	// OpenAsset opens the named file published with the version, returns ErrAssetNotFound
+	// when there is none.
+	OpenAsset(ctx context.Context, v Version, name string) (io.ReadCloser, error)
+}
+
+// OpenAsset opens the named file published with the version by the source, returns
+// ErrAssetNotFound when there is none or when the source doesn't publish any file.
+func OpenAsset(ctx context.Context, s Source, v Version, name string) (io.ReadCloser, error) {
+	if as, ok := s.(AssetSource); ok {
+		return as.OpenAsset(ctx, v, name)
+	}
+	return nil, fmt.Errorf("%w: no %s asset in %s", ErrAssetNotFound, name, v.TagName)
+}
+
+// SourceFunc selects the Source to fetch a repository from.
+type SourceFunc func(r config.Repository) (Source, error)
+
+// GitHubClients returns the clients reaching a GitHub host: the REST client of its API, and
+// the HTTP client downloading the release assets through the asset API. The latter must
+// carry the host credentials for private repositories to work.
+type GitHubClients func(host string) (*api.RESTClient, *http.Client, error)
+
+// Clients holds the clients used to reach the remote repositories.
+type Clients struct {
+	// GitHub reaches the GitHub hosts, github.com as well as GitHub Enterprise ones.
+	GitHub GitHubClients
+	// HTTP downloads from any other host, http.DefaultClient when nil.
+	HTTP *http.Client
+	// Registry is the transport reaching the OCI registries, the default one when nil.
+	Registry http.RoundTripper
+	// GitSettings are passed to the git commands ("key=value"), to configure the proxy or
+	// the CA bundle like the HTTP clients.
+	GitSettings []string
+	// GitDir is the directory the git repositories are mirrored in, the catalog-cd/git folder
+	// of the user cache directory when empty.
+	GitDir string
+	// Cache keeps the contracts published as GitHub release assets, nil not to.
+	Cache *cache.Cache
+}
+
+// DefaultSources selects the Source based on the repository provider, reaching the remote
+// repositories with the given clients.
+func DefaultSources(clients Clients) SourceFunc {
+	return func(r config.Repository) (Source, error) {
+		switch r.GetProvider() {
+		case config.ProviderGitLab:
+			return NewGitLabSource(r, clients.HTTP)
+		case config.ProviderGitHub:
+			if clients.GitHub == nil {
+				return nil, fmt.Errorf("no GitHub client to fetch %s", r.URL)
+			}
+			u, err := url.Parse(r.URL)
+			if err != nil {
+				return nil, err
+			}
+			client, assets, err := clients.GitHub(u.Host)
+			if err != nil {
+				return nil, err
+			}
+			s, err := NewGitHubSource(r, client, assets)
+			if err != nil {
+				return nil, err
+			}
+			s.contracts = clients.Cache
+			return s, nil
+		case config.ProviderLocal:
+			return NewLocalSource(r)
+		case config.ProviderGit:
+			gitDir := clients.GitDir
+			if gitDir == "" {
+				cacheDir, err := os.UserCacheDir()
+				if err != nil {
+					return nil, err
+				}
+				gitDir = filepath.Join(cacheDir, "catalog-cd", "git")
+			}
+			return NewGitSource(r, gitDir, clients.GitSettings...)
+		case config.ProviderOCI:
+			return NewOCISource(r, clients.Registry)
+		default:
+			return nil, fmt.Errorf("unsupported provider %q for %s", r.GetProvider(), r.URL)
+		}
+	}
+}
+
+// assetOpener opens the content of a release asset.
+type assetOpener func(ctx context.Context, a Asset) (io.ReadCloser, error)
+
+// downloadResources opens the resources tarball asset of the version.
+func downloadResources(ctx context.Context, open assetOpener, r config.Repository, v Version) (io.ReadCloser, error) {
+	a, ok := findAsset(v.Assets, r.ResourcesTarballName)
+	if !ok {
+		return nil, fmt.Errorf("%w: no %s asset in %s", ErrResourcesNotFound, r.ResourcesTarballName, v.TagName)
+	}
+	return open(ctx, a)
+}
+
+// openNamedAsset opens the named asset of the version.
+func openNamedAsset(ctx context.Context, open assetOpener, v Version, name string) (io.ReadCloser, error) {
+	a, ok := findAsset(v.Assets, name)
+	if !ok {
+		return nil, fmt.Errorf("%w: no %s asset in %s", ErrAssetNotFound, name, v.TagName)
+	}
+	return open(ctx, a)
+}
+
+// download issues a GET request on the url with the client, returning the response body on
+// success. Extra headers are set on the request as key, value pairs.
+func download(ctx context.Context, client *http.Client, url string, headers ...string) (io.ReadCloser, error) {
+	req, err := newGetRequest(ctx, url, headers...)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("status error: %v", resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+// isPreRelease reports whether the tag is a semantic version pre-release, for the sources
+// without release metadata to tell.
+func isPreRelease(r config.Repository, tag string) bool {
+	name, ok := r.TagVersion(tag)
+	if !ok {
+		return false
+	}
+	v, err := semver.ParseTolerant(name)
+	return err == nil && len(v.Pre) > 0
+}
+
+// newGetRequest returns a GET request on the url, extra headers are set on the request as key,
+// value pairs.
+func newGetRequest(ctx context.Context, url string, headers ...string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	return req, nil
+}
+
+// fetchContractAsset loads the contract from the version assets, "catalog.yml" is there for
+// backward-compatibility.
+func fetchContractAsset(ctx context.Context, open assetOpener, r config.Repository, v Version) (*contract.Contract, error) {
+	a, ok := findAsset(v.Assets, r.CatalogName, "catalog.yml")
+	if !ok {
+		return nil, fmt.Errorf("%w: no %s asset in %s", ErrContractNotFound, r.CatalogName, v.TagName)
+	}
+	body, err := open(ctx, a)
+	if err != nil {
+		return nil, fmt.Errorf("could not load contract from %s: %w", a.DownloadURL, err)
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("could not load contract from %s: %w", a.DownloadURL, err)
+	}
+	return contract.NewContractFromData(data)
+}
+
+// findAsset looks for the first asset matching one of the given names.
+func findAsset(assets []Asset, names ...string) (Asset, bool) {
+	for _, a := range assets {
+		for _, name := range names {
+			if a.Name == name {
+				return a, true
+			}
+		}
+	}
+	return Asset{}, false
+}
+
+// capVersions truncates the versions listed so far to the repository max-releases, more
+// tells whether more pages are left to list. It reports whether the listing must stop,
+// warning when older releases are dropped.
+func capVersions(r config.Repository, versions []Version, more bool) ([]Version, bool) {
+	if r.MaxReleases <= 0 || len(versions) < r.MaxReleases {
+		return versions, false
+	}
+	if more || len(versions) > r.MaxReleases {
+		fmt.Fprintf(os.Stderr, "# WARNING: %s has more than %d releases, older releases are ignored\n", r.URL, r.MaxReleases)
+	}
+	return versions[:r.MaxReleases], true
+}
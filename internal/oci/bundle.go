@@ -0,0 +1,80 @@
+package oci
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// BundleKindAnnotation layer annotation holding the kind of the bundled Tekton resource.
+	BundleKindAnnotation = "dev.tekton.image.kind"
+	// BundleNameAnnotation layer annotation holding the name of the bundled Tekton resource.
+	BundleNameAnnotation = "dev.tekton.image.name"
+)
+
+var (
+	// ErrBundleNotPinned marks a bundle reference without digest, which can't be verified.
+	ErrBundleNotPinned = errors.New("bundle reference is not pinned by digest")
+	// ErrBundleTooLarge marks a bundled resource above the size allowed.
+	ErrBundleTooLarge = errors.New("bundled resource too large")
+)
+
+// FetchBundleResource fetches the Tekton resource of the given kind and name from the bundle
+// reference, returning it as YAML. The reference must be pinned by digest, the manifest and
+// the layer digests being verified while pulling. Resources above maxSize bytes are rejected
+// without being read.
+func FetchBundleResource(ctx context.Context, ref, kind, resourceName string, maxSize int64, opts ...remote.Option) ([]byte, error) {
+	if !strings.Contains(ref, "@") {
+		return nil, fmt.Errorf("%w: %s", ErrBundleNotPinned, ref)
+	}
+	d, err := name.NewDigest(ref)
+	if err != nil {
+		return nil, err
+	}
+	img, err := remote.Image(d, options(ctx, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	m, err := img.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	for _, desc := range m.Layers {
+		if !strings.EqualFold(desc.Annotations[BundleKindAnnotation], kind) ||
+			desc.Annotations[BundleNameAnnotation] != resourceName {
+			continue
+		}
+		l, err := img.LayerByDigest(desc.Digest)
+		if err != nil {
+			return nil, err
+		}
+		rc, err := l.Uncompressed()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		// a bundle layer is an archive holding the resource as a single file
+		tr := tar.NewReader(rc)
+		header, err := tr.Next()
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s %s from %s: %w", kind, resourceName, ref, err)
+		}
+		if header.Size > maxSize {
+			return nil, fmt.Errorf("%w: %s %s from %s is %d bytes, above the %d bytes allowed", ErrBundleTooLarge, kind, resourceName, ref, header.Size, maxSize)
+		}
+		data, err := io.ReadAll(io.LimitReader(tr, maxSize))
+		if err != nil {
+			return nil, err
+		}
+		return yaml.JSONToYAML(data)
+	}
+	return nil, fmt.Errorf("%w: no %s %s in bundle %s", ErrLayerNotFound, kind, resourceName, ref)
+}
@@ -0,0 +1,135 @@
+// Package oci publishes and reads catalog releases as OCI artifacts, the contract and the
+// resources tarball being stored as layers of an artifact tagged with the release version.
+package oci
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+const (
+	// Scheme prefixes the OCI repositories URLs.
+	Scheme = "oci://"
+	// ArtifactType media type of the artifact configuration.
+	ArtifactType types.MediaType = "application/vnd.openshift-pipelines.catalog-cd.release.v1+json"
+	// ContractMediaType media type of the contract layer.
+	ContractMediaType types.MediaType = "application/vnd.openshift-pipelines.catalog-cd.contract.v1+yaml"
+	// ResourcesMediaType media type of the resources tarball layer.
+	ResourcesMediaType types.MediaType = "application/vnd.openshift-pipelines.catalog-cd.resources.v1.tar+gzip"
+	// titleAnnotation holds the original file name of a layer.
+	titleAnnotation = "org.opencontainers.image.title"
+)
+
+// ErrLayerNotFound marks an artifact without a layer of the expected media type.
+var ErrLayerNotFound = errors.New("layer not found")
+
+// options returns the remote options used to reach registries, credentials are taken from
+// the docker configuration. The extra options, such as the transport, come last.
+func options(ctx context.Context, extra ...remote.Option) []remote.Option {
+	return append([]remote.Option{
+		remote.WithContext(ctx),
+		remote.WithAuthFromKeychain(authn.DefaultKeychain),
+	}, extra...)
+}
+
+// Transport returns the options reaching the registries through the transport, none when it
+// is nil and the default transport is used.
+func Transport(t http.RoundTripper) []remote.Option {
+	if t == nil {
+		return nil
+	}
+	return []remote.Option{remote.WithTransport(t)}
+}
+
+// Push publishes the contract and the resources tarball files as an artifact, on the tagged
+// reference (registry/repository:tag).
+func Push(ctx context.Context, ref, contractFile, resourcesFile string, opts ...remote.Option) error {
+	tag, err := name.NewTag(ref)
+	if err != nil {
+		return err
+	}
+	img := mutate.MediaType(empty.Image, types.OCIManifestSchema1)
+	img = mutate.ConfigMediaType(img, ArtifactType)
+	for _, l := range []struct {
+		file      string
+		mediaType types.MediaType
+	}{
+		{contractFile, ContractMediaType},
+		{resourcesFile, ResourcesMediaType},
+	} {
+		data, err := os.ReadFile(l.file)
+		if err != nil {
+			return err
+		}
+		img, err = mutate.Append(img, mutate.Addendum{
+			Layer:       static.NewLayer(data, l.mediaType),
+			Annotations: map[string]string{titleAnnotation: filepath.Base(l.file)},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return remote.Write(tag, img, options(ctx, opts...)...)
+}
+
+// ListTags lists the tags of the repository (registry/repository).
+func ListTags(ctx context.Context, repository string, opts ...remote.Option) ([]string, error) {
+	repo, err := name.NewRepository(repository)
+	if err != nil {
+		return nil, err
+	}
+	return remote.List(repo, options(ctx, opts...)...)
+}
+
+// Image fetches the artifact of the reference.
+func Image(ctx context.Context, ref string, opts ...remote.Option) (v1.Image, error) {
+	r, err := name.ParseReference(ref)
+	if err != nil {
+		return nil, err
+	}
+	return remote.Image(r, options(ctx, opts...)...)
+}
+
+// OpenLayer opens the content of the first artifact layer of the given media type.
+func OpenLayer(img v1.Image, mediaType types.MediaType) (io.ReadCloser, error) {
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range layers {
+		mt, err := l.MediaType()
+		if err != nil {
+			return nil, err
+		}
+		if mt != mediaType {
+			continue
+		}
+		rc, err := l.Compressed()
+		if err != nil {
+			return nil, err
+		}
+		// reading it fully, so the layer digest gets verified before handing the content
+		defer rc.Close()
+		data, err := io.ReadAll(rc)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrLayerNotFound, mediaType)
+}
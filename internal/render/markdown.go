@@ -1,6 +1,7 @@
 package render
 
 import (
+	"io"
 	"text/template"
 
 	"github.com/openshift-pipelines/catalog-cd/internal/config"
@@ -37,6 +38,11 @@ func (m *Markdown) templateInputs() (map[string][]interface{}, error) {
 
 // Render instantiate a new template using the local functions to render the resource as markdown.
 func (m *Markdown) Render() error {
+	return m.Execute(m.cfg.Stream.Out)
+}
+
+// Execute renders the resource as markdown on the writer.
+func (m *Markdown) Execute(w io.Writer) error {
 	tpl, err := template.New("markdown").Funcs(templateFuncMap).Parse(string(markdownTemplate))
 	if err != nil {
 		return err
@@ -45,7 +51,7 @@ func (m *Markdown) Render() error {
 	if err != nil {
 		return err
 	}
-	return tpl.Execute(m.cfg.Stream.Out, inputs)
+	return tpl.Execute(w, inputs)
 }
 
 // NewMarkdown instantiates the markdown render by decoding the informed resource file.
@@ -0,0 +1,135 @@
+// Package transport holds the HTTP transports shared by the clients reaching remote
+// repositories.
+package transport
+
+import (
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Retry is a http.RoundTripper retrying idempotent requests on transient failures: network
+// errors, 5xx responses and rate limits. It waits as long as told by the Retry-After and
+// X-RateLimit-Reset headers, or backs off exponentially otherwise.
+type Retry struct {
+	// Next is the transport actually sending the requests, http.DefaultTransport when nil.
+	Next http.RoundTripper
+	// Log receives a line each time a request is retried, with the time waited.
+	Log io.Writer
+	// MaxRetries is the number of retries before giving up.
+	MaxRetries int
+	// MinBackoff and MaxBackoff bound the exponential backoff.
+	MinBackoff, MaxBackoff time.Duration
+	// MaxWait is the longest wait accepted before a retry, the failure being returned as-is
+	// when the rate limit resets later than that.
+	MaxWait time.Duration
+}
+
+// NewRetry instantiates a Retry transport with the default settings, logging in log.
+func NewRetry(next http.RoundTripper, log io.Writer) *Retry {
+	return &Retry{
+		Next:       next,
+		Log:        log,
+		MaxRetries: 5,
+		MinBackoff: time.Second,
+		MaxBackoff: time.Minute,
+		MaxWait:    15 * time.Minute,
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Retry) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if !replayable(req) {
+		return next.RoundTrip(req)
+	}
+	for attempt := 0; ; attempt++ {
+		resp, err := next.RoundTrip(req)
+		if attempt >= t.MaxRetries || !t.retryable(req, resp, err) {
+			return resp, err
+		}
+		wait := t.wait(resp, attempt)
+		if wait > t.MaxWait {
+			return resp, err
+		}
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if t.Log != nil {
+			fmt.Fprintf(t.Log, "# %s %s: %s, retrying in %s\n", req.Method, req.URL.Redacted(), reason, wait.Truncate(time.Millisecond))
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// replayable tells whether the request can safely be sent again.
+func replayable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return req.Body == nil || req.Body == http.NoBody
+	default:
+		return false
+	}
+}
+
+// retryable tells whether the outcome of the request is a transient failure.
+func (t *Retry) retryable(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		return req.Context().Err() == nil
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return true
+	case resp.StatusCode == http.StatusForbidden:
+		// GitHub reports primary and secondary rate limits with a 403.
+		return resp.Header.Get("Retry-After") != "" || resp.Header.Get("X-RateLimit-Remaining") == "0"
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return resp.StatusCode != http.StatusNotImplemented
+	default:
+		return false
+	}
+}
+
+// wait returns how long to wait before the next attempt, as told by the response headers or
+// following an exponential backoff.
+func (t *Retry) wait(resp *http.Response, attempt int) time.Duration {
+	if resp != nil {
+		if after := resp.Header.Get("Retry-After"); after != "" {
+			if seconds, err := strconv.Atoi(after); err == nil {
+				return max(time.Duration(seconds)*time.Second, 0)
+			}
+			if date, err := http.ParseTime(after); err == nil {
+				return max(time.Until(date), 0)
+			}
+		}
+		if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+				return max(time.Until(time.Unix(reset, 0)), 0)
+			}
+		}
+	}
+	backoff := t.MaxBackoff
+	if attempt < 32 && t.MinBackoff<<attempt < t.MaxBackoff {
+		backoff = t.MinBackoff << attempt
+	}
+	// Jitter spreads the retries of concurrent requests.
+	return backoff/2 + rand.N(backoff/2+1) // nolint:gosec
+}
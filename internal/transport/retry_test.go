@@ -0,0 +1,122 @@
+package transport_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/transport"
+)
+
+func TestRetry(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		failures []func(w http.ResponseWriter)
+		status   int
+		requests int
+		logs     int
+	}{{
+		name: "server errors",
+		failures: []func(w http.ResponseWriter){
+			func(w http.ResponseWriter) { w.WriteHeader(http.StatusBadGateway) },
+			func(w http.ResponseWriter) { w.WriteHeader(http.StatusServiceUnavailable) },
+		},
+		status:   http.StatusOK,
+		requests: 3,
+		logs:     2,
+	}, {
+		name: "secondary rate limit",
+		failures: []func(w http.ResponseWriter){
+			func(w http.ResponseWriter) {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusForbidden)
+			},
+		},
+		status:   http.StatusOK,
+		requests: 2,
+		logs:     1,
+	}, {
+		name: "primary rate limit",
+		failures: []func(w http.ResponseWriter){
+			func(w http.ResponseWriter) {
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Unix(), 10))
+				w.WriteHeader(http.StatusForbidden)
+			},
+		},
+		status:   http.StatusOK,
+		requests: 2,
+		logs:     1,
+	}, {
+		name: "rate limit resetting too late",
+		failures: []func(w http.ResponseWriter){
+			func(w http.ResponseWriter) {
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+				w.WriteHeader(http.StatusForbidden)
+			},
+		},
+		status:   http.StatusForbidden,
+		requests: 1,
+	}, {
+		name: "permission denied",
+		failures: []func(w http.ResponseWriter){
+			func(w http.ResponseWriter) { w.WriteHeader(http.StatusForbidden) },
+		},
+		status:   http.StatusForbidden,
+		requests: 1,
+	}, {
+		name: "too many failures",
+		failures: []func(w http.ResponseWriter){
+			func(w http.ResponseWriter) { w.WriteHeader(http.StatusInternalServerError) },
+			func(w http.ResponseWriter) { w.WriteHeader(http.StatusInternalServerError) },
+			func(w http.ResponseWriter) { w.WriteHeader(http.StatusInternalServerError) },
+			func(w http.ResponseWriter) { w.WriteHeader(http.StatusInternalServerError) },
+		},
+		status:   http.StatusInternalServerError,
+		requests: 4,
+		logs:     3,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				requests++
+				if requests <= len(tc.failures) {
+					tc.failures[requests-1](w)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			t.Cleanup(server.Close)
+
+			log := &bytes.Buffer{}
+			retry := transport.NewRetry(nil, log)
+			retry.MaxRetries = 3
+			retry.MinBackoff = time.Millisecond
+			retry.MaxBackoff = 10 * time.Millisecond
+			retry.MaxWait = time.Minute
+			client := &http.Client{Transport: retry}
+
+			req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tc.status || requests != tc.requests {
+				t.Fatalf("Should have got %d after %d requests, got %d after %d", tc.status, tc.requests, resp.StatusCode, requests)
+			}
+			if logs := strings.Count(log.String(), "retrying in"); logs != tc.logs {
+				t.Fatalf("Should have logged %d retries, got %q", tc.logs, log.String())
+			}
+		})
+	}
+}